@@ -12,6 +12,8 @@ import (
 type Node interface {
 	TokenLiteral() string // literal value of the token that node is associated with
 	String() string       // source code representation of all data
+	Pos() token.Position  // position of the first character of the node
+	End() token.Position  // position right after the last character of the node
 }
 
 type Statement interface {
@@ -47,6 +49,18 @@ func (p *Program) TokenLiteral() string {
 		return ""
 	}
 }
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
 
 // structure: let + ident + assign + expression;
 type LetStatement struct {
@@ -71,6 +85,13 @@ func (ls *LetStatement) String() string {
 }
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	return ls.Token.End
+}
 
 type ReturnStatement struct {
 	Token       token.Token // token.RETURN
@@ -91,6 +112,13 @@ func (rs *ReturnStatement) String() string {
 }
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
 
 // -x and !x
 type PrefixExpression struct {
@@ -104,6 +132,13 @@ func (pe *PrefixExpression) String() string {
 }
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
 
 type InfixExpression struct {
 	Token    token.Token // operator token: +, -, *
@@ -117,6 +152,13 @@ func (ie *InfixExpression) String() string {
 }
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position  { return ie.Left.Pos() }
+func (ie *InfixExpression) End() token.Position {
+	if ie.Right != nil {
+		return ie.Right.End()
+	}
+	return ie.Token.End
+}
 
 type ExpressionStatement struct {
 	Token      token.Token // first token of expression
@@ -131,6 +173,13 @@ func (es *ExpressionStatement) String() string {
 }
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos }
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
 
 type Identifier struct {
 	Token token.Token // token.IDENT
@@ -140,6 +189,8 @@ type Identifier struct {
 func (i *Identifier) String() string       { return i.Value }
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
+func (i *Identifier) End() token.Position  { return i.Token.End }
 
 // syntax:
 // class {
@@ -153,6 +204,8 @@ type ClassLiteral struct {
 
 func (cl *ClassLiteral) expressionNode()      {}
 func (cl *ClassLiteral) TokenLiteral() string { return cl.Token.Literal }
+func (cl *ClassLiteral) Pos() token.Position  { return cl.Token.Pos }
+func (cl *ClassLiteral) End() token.Position  { return cl.Token.End }
 func (cl *ClassLiteral) String() string {
 	var out bytes.Buffer
 
@@ -172,10 +225,18 @@ type FunctionLiteral struct {
 	Token      token.Token // token.FUNCTION
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Name       string // name of let binding function is bound to, empty if anonymous
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -201,6 +262,19 @@ type IntegerLiteral struct {
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position  { return il.Token.End }
+
+type FloatLiteral struct {
+	Token token.Token // token.FLOAT
+	Value float64
+}
+
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position  { return fl.Token.End }
 
 type Boolean struct {
 	Token token.Token
@@ -210,6 +284,8 @@ type Boolean struct {
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
+func (b *Boolean) End() token.Position  { return b.Token.End }
 
 type StringLiteral struct {
 	Token token.Token // token.STRING
@@ -219,14 +295,43 @@ type StringLiteral struct {
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Value }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End }
+
+// "text ${expr} text": parts are string literals and embedded expressions in order
+type InterpolatedString struct {
+	Token token.Token // token.TEMPLATE
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Pos() token.Position  { return is.Token.Pos }
+func (is *InterpolatedString) End() token.Position  { return is.Token.End }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteString("\"")
+	for _, part := range is.Parts {
+		if sl, ok := part.(*StringLiteral); ok {
+			out.WriteString(sl.Value)
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString("\"")
+	return out.String()
+}
 
 type ArrayLiteral struct {
 	Token    token.Token // [ token
 	Elements []Expression
+	Closing  token.Token // ] token
 }
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position  { return al.Closing.End }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -242,12 +347,15 @@ func (al *ArrayLiteral) String() string {
 }
 
 type HashLiteral struct {
-	Token token.Token
-	Pairs map[Expression]Expression
+	Token   token.Token // { token
+	Pairs   map[Expression]Expression
+	Closing token.Token // } token
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position  { return hl.Closing.End }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
@@ -263,13 +371,16 @@ func (hl *HashLiteral) String() string {
 }
 
 type IndexExpression struct {
-	Token token.Token
-	Index Expression
-	Left  Expression
+	Token   token.Token // [ token
+	Index   Expression
+	Left    Expression
+	Closing token.Token // ] token
 }
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Left.Pos() }
+func (ie *IndexExpression) End() token.Position  { return ie.Closing.End }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -292,6 +403,16 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return ie.Token.End
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -312,10 +433,13 @@ type CallExpression struct {
 	Token     token.Token // (
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
+	Closing   token.Token // )
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Function.Pos() }
+func (ce *CallExpression) End() token.Position  { return ce.Closing.End }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -335,10 +459,13 @@ func (ce *CallExpression) String() string {
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
+	Closing    token.Token // the } token
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position  { return bs.Closing.End }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
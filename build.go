@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Savvelius/go-interp/compiler"
+	"github.com/Savvelius/go-interp/object"
+	"github.com/Savvelius/go-interp/vm"
+)
+
+// build <file> [-o out]: compiles source file into .monkeyc file next to it
+func build(args []string) int {
+	if len(args) != 1 && !(len(args) == 3 && args[1] == "-o") {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	src := args[0]
+	out := strings.TrimSuffix(src, filepath.Ext(src)) + compiler.FileExtension
+	if len(args) == 3 {
+		out = args[2]
+	}
+
+	bytecode, err := compileFile(src)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", src, err)
+		return 1
+	}
+
+	err = os.WriteFile(out, data, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// exec <file.monkeyc> [args...]: runs compiled file on the vm,
+// args are available to it as `args` array of strings
+func execCompiled(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	bytecode, err := loadCompiledFile(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	globals := make([]object.Object, vm.GlobalsSize)
+	globals[argsGlobal] = argsArray(args[1:])
+
+	machine := vm.NewWithGlobalsStore(bytecode, globals)
+	err = machine.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: runtime error: %s\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
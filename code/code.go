@@ -16,19 +16,40 @@ Instructions: contiguous piece of instructions merged as []byte - repr of the pr
 type Instructions []byte
 
 func (ins Instructions) String() string {
+	return ins.Disassemble(nil)
+}
+
+// Annotator returns comment for a decoded instruction, empty string for no comment
+type Annotator func(op Opcode, operands []int) string
+
+// formats every instruction on its own line, prefixed with its offset.
+// Unknown opcodes and truncated operands are reported inline and skipped
+func (ins Instructions) Disassemble(annotate Annotator) string {
 	var out bytes.Buffer
 
 	i := 0
 	for i < len(ins) {
 		def, err := Lookup(ins[i])
 		if err != nil {
-			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			fmt.Fprintf(&out, "%04d ERROR: %s\n", i, err)
+			i++
 			continue
 		}
 
+		if i+1+def.width() > len(ins) {
+			fmt.Fprintf(&out, "%04d ERROR: %s is truncated\n", i, def.Name)
+			break
+		}
+
 		operands, read := ReadOperands(def, ins[i+1:])
 
-		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+		line := ins.fmtInstruction(def, operands)
+		if annotate != nil {
+			if comment := annotate(Opcode(ins[i]), operands); comment != "" {
+				line = fmt.Sprintf("%-24s ; %s", line, comment)
+			}
+		}
+		fmt.Fprintf(&out, "%04d %s\n", i, line)
 
 		// opcode size + num bytes read by ReadOperands
 		i += 1 + read
@@ -40,16 +61,17 @@ func (ins Instructions) String() string {
 func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 	operandCount := len(def.OperandWidths)
 	if len(operands) != operandCount {
-		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n",
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d",
 			len(operands), operandCount)
 	}
 
-	switch operandCount {
-	case 1:
-		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	var out bytes.Buffer
+	out.WriteString(def.Name)
+	for _, operand := range operands {
+		fmt.Fprintf(&out, " %d", operand)
 	}
 
-	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+	return out.String()
 }
 
 // byte repr of operation
@@ -58,6 +80,57 @@ type Opcode byte
 // enum of opcodes
 const (
 	OpConstant Opcode = iota
+	OpPop             // pops top of the stack, emitted after expression statements
+
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+
+	OpEqual
+	OpNotEqual
+	OpGreaterThan
+	OpGreaterThanOrEqual
+
+	OpTrue
+	OpFalse
+	OpNull
+
+	OpMinus // -x
+	OpBang  // !x
+
+	OpJumpNotTruthy // pops condition, jumps to operand if it's not truthy
+	OpJump          // unconditionally jumps to operand
+
+	// used by `and` and `or`: jump keeping condition on the stack as the result,
+	// otherwise pop it and continue with the right operand
+	OpJumpNotTruthyOrPop
+	OpJumpTruthyOrPop
+
+	OpSetGlobal
+	OpGetGlobal
+	OpSetLocal
+	OpGetLocal
+
+	OpGetBuiltin
+	OpGetFree
+	OpCurrentClosure // pushes closure that is being executed, used for recursion
+
+	OpCall        // operand is number of arguments on top of the callee
+	OpReturnValue // returns value on top of the stack
+	OpReturn      // returns null, emitted for functions without return value
+	OpClosure     // wraps compiled function constant and free variables into closure
+
+	OpArray // operand is number of elements on the stack
+	OpHash  // operand is number of keys and values on the stack, twice the number of pairs
+	OpIndex
+
+	OpInterpolate // operand is number of parts on the stack, joined into one string
+
+	// operands are evaluated left to right, same as in evaluator
+	OpLessThan
+	OpLessThanOrEqual
 )
 
 // info about specific Opcode
@@ -68,6 +141,63 @@ type Definition struct {
 
 var definitions = map[Opcode]*Definition{
 	OpConstant: {"OpConstant", []int{2}}, // max 2**16 constants
+	OpPop:      {"OpPop", []int{}},
+
+	OpAdd: {"OpAdd", []int{}},
+	OpSub: {"OpSub", []int{}},
+	OpMul: {"OpMul", []int{}},
+	OpDiv: {"OpDiv", []int{}},
+	OpMod: {"OpMod", []int{}},
+
+	OpEqual:              {"OpEqual", []int{}},
+	OpNotEqual:           {"OpNotEqual", []int{}},
+	OpGreaterThan:        {"OpGreaterThan", []int{}},
+	OpGreaterThanOrEqual: {"OpGreaterThanOrEqual", []int{}},
+
+	OpTrue:  {"OpTrue", []int{}},
+	OpFalse: {"OpFalse", []int{}},
+	OpNull:  {"OpNull", []int{}},
+
+	OpMinus: {"OpMinus", []int{}},
+	OpBang:  {"OpBang", []int{}},
+
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}}, // absolute offset into instructions
+	OpJump:          {"OpJump", []int{2}},
+
+	OpJumpNotTruthyOrPop: {"OpJumpNotTruthyOrPop", []int{2}},
+	OpJumpTruthyOrPop:    {"OpJumpTruthyOrPop", []int{2}},
+
+	OpSetGlobal: {"OpSetGlobal", []int{2}}, // max 2**16 globals
+	OpGetGlobal: {"OpGetGlobal", []int{2}},
+	OpSetLocal:  {"OpSetLocal", []int{1}}, // max 256 locals per function
+	OpGetLocal:  {"OpGetLocal", []int{1}},
+
+	OpGetBuiltin:     {"OpGetBuiltin", []int{1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+	OpClosure:     {"OpClosure", []int{2, 1}}, // constant index, number of free variables
+
+	OpArray: {"OpArray", []int{2}},
+	OpHash:  {"OpHash", []int{2}},
+	OpIndex: {"OpIndex", []int{}},
+
+	OpInterpolate: {"OpInterpolate", []int{2}},
+
+	OpLessThan:        {"OpLessThan", []int{}},
+	OpLessThanOrEqual: {"OpLessThanOrEqual", []int{}},
+}
+
+// total size of operands in bytes
+func (d *Definition) width() int {
+	width := 0
+	for _, w := range d.OperandWidths {
+		width += w
+	}
+	return width
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -87,10 +217,7 @@ func Make(op Opcode, operands ...int) []byte {
 	}
 
 	// 1 for opCode
-	instructionLen := 1
-	for _, w := range def.OperandWidths {
-		instructionLen += w
-	}
+	instructionLen := 1 + def.width()
 
 	instruction := make([]byte, instructionLen)
 	instruction[0] = byte(op)
@@ -102,6 +229,8 @@ func Make(op Opcode, operands ...int) []byte {
 		switch width {
 		case 2:
 			binary.BigEndian.PutUint16(instruction[offset:], uint16(operand))
+		case 1:
+			instruction[offset] = byte(operand)
 		}
 
 		offset += width
@@ -119,6 +248,8 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 		switch width {
 		case 2:
 			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
 		}
 
 		offset += width
@@ -130,3 +261,7 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 func ReadUint16(ins Instructions) uint16 {
 	return binary.BigEndian.Uint16(ins)
 }
+
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
@@ -11,6 +11,10 @@ func TestMake(t *testing.T) {
 		expected []byte
 	}{
 		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+		{OpInterpolate, []int{3}, []byte{byte(OpInterpolate), 0, 3}},
 	}
 	for _, tt := range tests {
 		instruction := Make(tt.op, tt.operands...)
@@ -30,13 +34,17 @@ func TestMake(t *testing.T) {
 
 func TestInstructionsString(t *testing.T) {
 	instructions := []Instructions{
-		Make(OpConstant, 1),
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
 		Make(OpConstant, 2),
 		Make(OpConstant, 65535),
+		Make(OpClosure, 65535, 255),
 	}
-	expected := `0000 OpConstant 1
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
 0003 OpConstant 2
 0006 OpConstant 65535
+0009 OpClosure 65535 255
 `
 	concatted := Instructions{}
 	for _, ins := range instructions {
@@ -49,6 +57,47 @@ func TestInstructionsString(t *testing.T) {
 	}
 }
 
+func TestInstructionsStringMalformed(t *testing.T) {
+	tests := []struct {
+		ins      Instructions
+		expected string
+	}{
+		{
+			Instructions{255, byte(OpPop)},
+			"0000 ERROR: opcode 255 undefined\n0001 OpPop\n",
+		},
+		{
+			Instructions{byte(OpPop), byte(OpConstant), 1},
+			"0000 OpPop\n0001 ERROR: OpConstant is truncated\n",
+		},
+	}
+	for _, tt := range tests {
+		if actual := tt.ins.String(); actual != tt.expected {
+			t.Errorf("instructions wrongly formatted.\nwant=%q\ngot=%q",
+				tt.expected, actual)
+		}
+	}
+}
+
+func TestInstructionsDisassembleAnnotated(t *testing.T) {
+	ins := Instructions{}
+	ins = append(ins, Make(OpConstant, 1)...)
+	ins = append(ins, Make(OpPop)...)
+
+	annotate := func(op Opcode, operands []int) string {
+		if op == OpConstant {
+			return "constant"
+		}
+		return ""
+	}
+	expected := "0000 OpConstant 1             ; constant\n0003 OpPop\n"
+
+	if actual := ins.Disassemble(annotate); actual != expected {
+		t.Errorf("instructions wrongly disassembled.\nwant=%q\ngot=%q",
+			expected, actual)
+	}
+}
+
 func TestReadOperands(t *testing.T) {
 	tests := []struct {
 		op        Opcode
@@ -56,6 +105,8 @@ func TestReadOperands(t *testing.T) {
 		bytesRead int
 	}{
 		{OpConstant, []int{65535}, 2},
+		{OpGetLocal, []int{255}, 1},
+		{OpClosure, []int{65535, 255}, 3},
 	}
 	for _, tt := range tests {
 		instruction := Make(tt.op, tt.operands...)
@@ -1,23 +1,64 @@
 package compiler
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/Savvelius/go-interp/ast"
 	"github.com/Savvelius/go-interp/code"
 	"github.com/Savvelius/go-interp/object"
 )
 
 type Compiler struct {
-	instructions code.Instructions
-	constants    []object.Object
+	constants []object.Object
+
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope // one scope per function being compiled, main scope first
+	scopeIndex int
+}
+
+// instructions of a single function body
+type CompilationScope struct {
+	instructions        code.Instructions
+	lastInstruction     EmittedInstruction // very last emitted instruction
+	previousInstruction EmittedInstruction // one before lastInstruction
+}
+
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
 }
 
 func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	symbolTable := NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
 	return &Compiler{
-		instructions: code.Instructions{},
-		constants:    []object.Object{},
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
 	}
 }
 
+// creates compiler that continues where previous one stopped, used by REPL
+// to keep globals and constants across lines
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
 func (c *Compiler) Compile(node ast.Node) error {
 	switch node := node.(type) {
 	case *ast.Program:
@@ -32,7 +73,162 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			err := c.Compile(stmt)
+			if err != nil {
+				return err
+			}
+		}
+
+	case *ast.LetStatement:
+		// defined after value is compiled, so value sees the previous binding
+		// of the name. Functions refer to themselves through FunctionLiteral.Name
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		symbol := c.symbolTable.Define(node.Name.Value)
+		if symbol.Scope == GlobalScope {
+			c.emit(code.OpSetGlobal, symbol.Index)
+		} else {
+			c.emit(code.OpSetLocal, symbol.Index)
+		}
+
+	case *ast.ReturnStatement:
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpReturnValue)
+
+	case *ast.FunctionLiteral:
+		c.enterScope()
+
+		if node.Name != "" {
+			c.symbolTable.DefineFunctionName(node.Name)
+		}
+
+		for _, p := range node.Parameters {
+			c.symbolTable.Define(p.Value)
+		}
+
+		err := c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+
+		// last expression of the body is an implicit return value
+		if c.lastInstructionIs(code.OpPop) {
+			c.replaceLastPopWithReturn()
+		}
+		if !c.lastInstructionIs(code.OpReturnValue) {
+			c.emit(code.OpReturn)
+		}
+
+		freeSymbols := c.symbolTable.FreeSymbols
+		numLocals := c.symbolTable.numDefinitions
+		instructions := c.leaveScope()
+
+		// free variables are pushed on the stack, so OpClosure can collect them
+		for _, s := range freeSymbols {
+			c.loadSymbol(s)
+		}
+
+		compiledFn := &object.CompiledFunction{
+			Instructions:  instructions,
+			NumLocals:     numLocals,
+			NumParameters: len(node.Parameters),
+		}
+		fnIndex := c.addConstant(compiledFn)
+		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+
+	case *ast.CallExpression:
+		err := c.Compile(node.Function)
+		if err != nil {
+			return err
+		}
+
+		for _, arg := range node.Arguments {
+			err := c.Compile(arg)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpCall, len(node.Arguments))
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return fmt.Errorf("undefined variable %s", node.Value)
+		}
+
+		c.loadSymbol(symbol)
+
+	case *ast.IfExpression:
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+
+		// operand is patched after consequence is compiled
+		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+		err = c.Compile(node.Consequence)
+		if err != nil {
+			return err
+		}
+		// if is an expression, so consequence has to leave its value on the stack
+		if c.lastInstructionIs(code.OpPop) {
+			c.removeLastPop()
+		} else {
+			c.emit(code.OpNull)
+		}
+
+		jumpPos := c.emit(code.OpJump, 9999)
+		c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+		if node.Alternative == nil {
+			c.emit(code.OpNull)
+		} else {
+			err := c.Compile(node.Alternative)
+			if err != nil {
+				return err
+			}
+			if c.lastInstructionIs(code.OpPop) {
+				c.removeLastPop()
+			} else {
+				c.emit(code.OpNull)
+			}
+		}
+
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	case *ast.PrefixExpression:
+		err := c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
 	case *ast.InfixExpression:
+		if node.Operator == "and" || node.Operator == "or" {
+			return c.compileLogicalExpression(node)
+		}
+
 		err := c.Compile(node.Left)
 		if err != nil {
 			return err
@@ -43,24 +239,240 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 
+		switch node.Operator {
+		case "+":
+			c.emit(code.OpAdd)
+		case "-":
+			c.emit(code.OpSub)
+		case "*":
+			c.emit(code.OpMul)
+		case "/":
+			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case ">":
+			c.emit(code.OpGreaterThan)
+		case ">=":
+			c.emit(code.OpGreaterThanOrEqual)
+		case "<":
+			c.emit(code.OpLessThan)
+		case "<=":
+			c.emit(code.OpLessThanOrEqual)
+		case "==":
+			c.emit(code.OpEqual)
+		case "!=":
+			c.emit(code.OpNotEqual)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(integer))
+
+	case *ast.FloatLiteral:
+		fl := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(fl))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			err := c.Compile(part)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpInterpolate, len(node.Parts))
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		// map iteration order is random, sort keys to get deterministic bytecode
+		keys := []ast.Expression{}
+		for k := range node.Pairs {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+
+		for _, k := range keys {
+			err := c.Compile(k)
+			if err != nil {
+				return err
+			}
+			err = c.Compile(node.Pairs[k])
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	case *ast.IndexExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Index)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpIndex)
+
+	default:
+		err := &UnsupportedNodeError{Node: node}
+		if node != nil {
+			err.Pos = node.Pos()
+		}
+		return err
 	}
 
 	return nil
 }
 
+// right operand is skipped if left one decides the result, which is left on the stack
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+
+	op := code.OpJumpNotTruthyOrPop
+	if node.Operator == "or" {
+		op = code.OpJumpTruthyOrPop
+	}
+	// operand is patched after right side is compiled
+	jumpPos := c.emit(op, 9999)
+
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+// starts compiling new function body with its own symbol table
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// returns instructions of the function body that was being compiled
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
 // adds instruction to instruction pool, returns it's position
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	instruction := code.Make(op, operands...)
 	position := c.addInstruction(instruction)
+
+	c.setLastInstruction(op, position)
+
 	return position
 }
 
+func (c *Compiler) setLastInstruction(op code.Opcode, position int) {
+	scope := &c.scopes[c.scopeIndex]
+	scope.previousInstruction = scope.lastInstruction
+	scope.lastInstruction = EmittedInstruction{Opcode: op, Position: position}
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	scope := &c.scopes[c.scopeIndex]
+	scope.instructions = scope.instructions[:scope.lastInstruction.Position]
+	scope.lastInstruction = scope.previousInstruction
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+// overwrites instruction at given position. New instruction must have the same length
+func (c *Compiler) replaceInstruction(position int, newInstruction []byte) {
+	ins := c.currentInstructions()
+	for i := 0; i < len(newInstruction); i++ {
+		ins[position+i] = newInstruction[i]
+	}
+}
+
+// used for back-patching jump targets
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
 func (c *Compiler) addInstruction(instruction []byte) int {
-	position := len(c.instructions)
-	c.instructions = append(c.instructions, instruction...)
+	position := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), instruction...)
 	return position
 }
 
@@ -72,7 +484,7 @@ func (c *Compiler) addConstant(obj object.Object) int {
 
 func (c *Compiler) Bytecode() *Bytecode {
 	return &Bytecode{
-		Instructions: c.instructions,
+		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
 	}
 }
@@ -0,0 +1,115 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/Savvelius/go-interp/ast"
+)
+
+// every ast node type must either compile or be rejected with UnsupportedNodeError
+func TestCompileCoverage(t *testing.T) {
+	nodes := map[string]struct {
+		input     string
+		node      ast.Node // used when node can't be produced by parser
+		supported bool
+	}{
+		"Program":             {input: "1", supported: true},
+		"LetStatement":        {input: "let a = 1;", supported: true},
+		"ReturnStatement":     {input: "fn() { return 1; }", supported: true},
+		"ExpressionStatement": {input: "1;", supported: true},
+		"BlockStatement":      {input: "if (true) { 1 }", supported: true},
+		"PrefixExpression":    {input: "-1", supported: true},
+		"InfixExpression":     {input: "1 + 2", supported: true},
+		"Identifier":          {input: "let a = 1; a", supported: true},
+		"FunctionLiteral":     {input: "fn(a) { a }", supported: true},
+		"IntegerLiteral":      {input: "1", supported: true},
+		"FloatLiteral":        {input: "1.5", supported: true},
+		"Boolean":             {input: "true", supported: true},
+		"StringLiteral":       {input: `"a"`, supported: true},
+		"InterpolatedString":  {input: `"a${1}"`, supported: true},
+		"ArrayLiteral":        {input: "[1, 2]", supported: true},
+		"HashLiteral":         {input: "{1: 2}", supported: true},
+		"IndexExpression":     {input: "[1][0]", supported: true},
+		"IfExpression":        {input: "if (true) { 1 } else { 2 }", supported: true},
+		"CallExpression":      {input: "fn() { 1 }()", supported: true},
+		"ClassLiteral":        {node: &ast.ClassLiteral{}},
+	}
+
+	for _, name := range astNodeTypes(t) {
+		tt, ok := nodes[name]
+		if !ok {
+			t.Errorf("ast.%s is not covered by compiler coverage test", name)
+			continue
+		}
+
+		node := tt.node
+		if node == nil {
+			node = parse(tt.input)
+		}
+
+		err := New().Compile(node)
+		if tt.supported {
+			if err != nil {
+				t.Errorf("ast.%s - unexpected compiler error: %s", name, err)
+			}
+			continue
+		}
+
+		var unsupported *UnsupportedNodeError
+		if !errors.As(err, &unsupported) {
+			t.Errorf("ast.%s - expected UnsupportedNodeError, got=%T (%v)", name, err, err)
+			continue
+		}
+		if unsupported.TypeName() != fmt.Sprintf("*ast.%s", name) {
+			t.Errorf("ast.%s - wrong type name. got=%s", name, unsupported.TypeName())
+		}
+	}
+}
+
+func TestUnsupportedNodeIsNotDropped(t *testing.T) {
+	program := parse("1; 2")
+	program.Statements[1].(*ast.ExpressionStatement).Expression = &ast.ClassLiteral{}
+
+	err := New().Compile(program)
+	if _, ok := err.(*UnsupportedNodeError); !ok {
+		t.Fatalf("expected UnsupportedNodeError, got=%T (%v)", err, err)
+	}
+}
+
+// names of all types declared in ast package that implement TokenLiteral
+func astNodeTypes(t *testing.T) []string {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	pkgs, err := goparser.ParseDir(fset, "../ast", nil, 0)
+	if err != nil {
+		t.Fatalf("could not parse ast package: %s", err)
+	}
+
+	names := []string{}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*goast.FuncDecl)
+				if !ok || fn.Recv == nil || fn.Name.Name != "TokenLiteral" {
+					continue
+				}
+				recv := fn.Recv.List[0].Type
+				if star, ok := recv.(*goast.StarExpr); ok {
+					recv = star.X
+				}
+				names = append(names, recv.(*goast.Ident).Name)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		t.Fatalf("no ast node types found")
+	}
+	return names
+}
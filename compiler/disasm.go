@@ -0,0 +1,58 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Savvelius/go-interp/code"
+	"github.com/Savvelius/go-interp/object"
+)
+
+// human readable listing of main program followed by every compiled function
+// in the constant pool. References to constants are annotated with their values
+func (b *Bytecode) Disassemble() string {
+	var out bytes.Buffer
+
+	out.WriteString("main:\n")
+	out.WriteString(b.Instructions.Disassemble(b.annotate))
+
+	for i, constant := range b.Constants {
+		fn, ok := constant.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(&out, "\nconstant %d: fn (params=%d, locals=%d)\n",
+			i, fn.NumParameters, fn.NumLocals)
+		out.WriteString(fn.Instructions.Disassemble(b.annotate))
+	}
+
+	return out.String()
+}
+
+func (b *Bytecode) annotate(op code.Opcode, operands []int) string {
+	switch op {
+	case code.OpConstant, code.OpClosure:
+		return b.describeConstant(operands[0])
+	case code.OpGetBuiltin:
+		if operands[0] < len(object.Builtins) {
+			return object.Builtins[operands[0]].Name
+		}
+		return "<invalid builtin>"
+	default:
+		return ""
+	}
+}
+
+func (b *Bytecode) describeConstant(index int) string {
+	if index >= len(b.Constants) {
+		return "<invalid constant>"
+	}
+
+	switch constant := b.Constants[index].(type) {
+	case *object.CompiledFunction:
+		return fmt.Sprintf("fn constant %d", index)
+	default:
+		return constant.Inspect()
+	}
+}
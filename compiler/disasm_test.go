@@ -0,0 +1,35 @@
+package compiler
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	input := `let add = fn(a, b) { a + b }; add(1, len("ab"));`
+
+	compiler := New()
+	err := compiler.Compile(parse(input))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := `main:
+0000 OpClosure 0 0            ; fn constant 0
+0004 OpSetGlobal 0
+0007 OpGetGlobal 0
+0010 OpConstant 1             ; 1
+0013 OpGetBuiltin 0           ; len
+0015 OpConstant 2             ; "ab"
+0018 OpCall 1
+0020 OpCall 2
+0022 OpPop
+
+constant 0: fn (params=2, locals=2)
+0000 OpGetLocal 0
+0002 OpGetLocal 1
+0004 OpAdd
+0005 OpReturnValue
+`
+	actual := compiler.Bytecode().Disassemble()
+	if actual != expected {
+		t.Errorf("wrong disassembly.\nwant=%s\ngot=%s", expected, actual)
+	}
+}
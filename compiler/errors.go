@@ -0,0 +1,30 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/Savvelius/go-interp/ast"
+	"github.com/Savvelius/go-interp/token"
+)
+
+// returned by Compile for nodes that have no bytecode representation,
+// so they are never silently dropped from the output
+type UnsupportedNodeError struct {
+	Node ast.Node
+	Pos  token.Position // position of the node, invalid if unknown
+}
+
+// go type of the rejected node, e.g. *ast.ClassLiteral
+func (e *UnsupportedNodeError) TypeName() string {
+	return fmt.Sprintf("%T", e.Node)
+}
+
+func (e *UnsupportedNodeError) Error() string {
+	if e.Node == nil {
+		return fmt.Sprintf("unsupported node %s", e.TypeName())
+	}
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: unsupported node %s at %q", e.Pos, e.TypeName(), e.Node.TokenLiteral())
+	}
+	return fmt.Sprintf("unsupported node %s at %q", e.TypeName(), e.Node.TokenLiteral())
+}
@@ -0,0 +1,312 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+
+	"github.com/Savvelius/go-interp/code"
+	"github.com/Savvelius/go-interp/object"
+)
+
+/*
+.monkeyc file layout, all numbers are big endian:
+	magic        4 bytes "MNKC"
+	version      uint16
+	constants    uint32 count, then for each: 1 byte tag + payload
+		integer  int64
+		string   uint32 length + bytes
+		function uint32 locals, uint32 parameters, uint32 length + instructions
+		float    IEEE 754 bits as uint64
+	instructions uint32 length + bytes
+	checksum     uint32 CRC-32 (IEEE) of everything above
+*/
+
+const (
+	FileExtension = ".monkeyc"
+	FormatVersion = 1
+)
+
+var magic = []byte("MNKC")
+
+// constant pool tags
+const (
+	tagInteger byte = iota + 1
+	tagString
+	tagCompiledFunction
+	tagFloat
+)
+
+var (
+	ErrBadMagic         = errors.New("not a compiled monkey file")
+	ErrChecksumMismatch = errors.New("checksum mismatch, file is corrupted")
+	ErrTruncated        = errors.New("unexpected end of compiled file")
+	ErrInvalidBytecode  = errors.New("invalid bytecode")
+)
+
+func (b *Bytecode) MarshalBinary() ([]byte, error) {
+	var out bytes.Buffer
+
+	out.Write(magic)
+	writeUint16(&out, FormatVersion)
+
+	writeUint32(&out, uint32(len(b.Constants)))
+	for i, constant := range b.Constants {
+		err := writeConstant(&out, constant)
+		if err != nil {
+			return nil, fmt.Errorf("constant %d: %w", i, err)
+		}
+	}
+
+	writeBytes(&out, b.Instructions)
+
+	writeUint32(&out, crc32.ChecksumIEEE(out.Bytes()))
+
+	return out.Bytes(), nil
+}
+
+func (b *Bytecode) UnmarshalBinary(data []byte) error {
+	if len(data) < len(magic) || !bytes.Equal(data[:len(magic)], magic) {
+		return ErrBadMagic
+	}
+	if len(data) < len(magic)+2+4 {
+		return ErrTruncated
+	}
+
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return ErrChecksumMismatch
+	}
+
+	r := &reader{data: body, offset: len(magic)}
+
+	version := r.uint16()
+	if r.err == nil && version != FormatVersion {
+		return fmt.Errorf("unsupported format version %d, want %d", version, FormatVersion)
+	}
+
+	count := r.uint32()
+	constants := []object.Object{}
+	for i := 0; i < int(count) && r.err == nil; i++ {
+		constant, err := r.constant()
+		if err != nil {
+			return fmt.Errorf("constant %d: %w", i, err)
+		}
+		constants = append(constants, constant)
+	}
+
+	instructions := r.bytes()
+	if r.err != nil {
+		return r.err
+	}
+	if r.offset != len(body) {
+		return fmt.Errorf("%d unexpected trailing bytes", len(body)-r.offset)
+	}
+
+	err := validateInstructions(instructions, 0, constants)
+	if err != nil {
+		return fmt.Errorf("%w: main: %s", ErrInvalidBytecode, err)
+	}
+	for i, constant := range constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			err := validateInstructions(fn.Instructions, fn.NumLocals, constants)
+			if err != nil {
+				return fmt.Errorf("%w: function constant %d: %s", ErrInvalidBytecode, i, err)
+			}
+		}
+	}
+
+	b.Constants = constants
+	b.Instructions = code.Instructions(instructions)
+	return nil
+}
+
+// checks that ins can be run by the vm without reading past its end or
+// out of constant pool, builtins and locals. Checksum only protects against
+// corruption, files that weren't written by the compiler must be checked too
+func validateInstructions(ins code.Instructions, numLocals int, constants []object.Object) error {
+	starts := map[int]bool{}
+	jumps := [][2]int{} // offset of jump instruction and its target
+
+	for ip := 0; ip < len(ins); {
+		def, err := code.Lookup(ins[ip])
+		if err != nil {
+			return fmt.Errorf("at %d: %s", ip, err)
+		}
+		starts[ip] = true
+
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if ip+1+width > len(ins) {
+			return fmt.Errorf("at %d: %s operands are truncated", ip, def.Name)
+		}
+		operands, read := code.ReadOperands(def, ins[ip+1:])
+
+		switch code.Opcode(ins[ip]) {
+		case code.OpConstant:
+			if operands[0] >= len(constants) {
+				return fmt.Errorf("at %d: constant %d out of range, pool has %d", ip, operands[0], len(constants))
+			}
+		case code.OpClosure:
+			if operands[0] >= len(constants) {
+				return fmt.Errorf("at %d: constant %d out of range, pool has %d", ip, operands[0], len(constants))
+			}
+			if _, ok := constants[operands[0]].(*object.CompiledFunction); !ok {
+				return fmt.Errorf("at %d: closure of non-function constant %d", ip, operands[0])
+			}
+		case code.OpGetBuiltin:
+			if operands[0] >= len(object.Builtins) {
+				return fmt.Errorf("at %d: builtin %d out of range", ip, operands[0])
+			}
+		case code.OpGetLocal, code.OpSetLocal:
+			if operands[0] >= numLocals {
+				return fmt.Errorf("at %d: local %d out of range, function has %d", ip, operands[0], numLocals)
+			}
+		case code.OpJump, code.OpJumpNotTruthy, code.OpJumpNotTruthyOrPop, code.OpJumpTruthyOrPop:
+			jumps = append(jumps, [2]int{ip, operands[0]})
+		}
+
+		ip += 1 + read
+	}
+
+	// jumping to the end of instructions finishes them
+	for _, jump := range jumps {
+		if ip, target := jump[0], jump[1]; target != len(ins) && !starts[target] {
+			return fmt.Errorf("at %d: jump target %d is not an instruction", ip, target)
+		}
+	}
+	return nil
+}
+
+func writeConstant(out *bytes.Buffer, obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		out.WriteByte(tagInteger)
+		writeUint64(out, uint64(obj.Value))
+	case *object.String:
+		out.WriteByte(tagString)
+		writeBytes(out, []byte(obj.Value))
+	case *object.CompiledFunction:
+		out.WriteByte(tagCompiledFunction)
+		writeUint32(out, uint32(obj.NumLocals))
+		writeUint32(out, uint32(obj.NumParameters))
+		writeBytes(out, obj.Instructions)
+	case *object.Float:
+		out.WriteByte(tagFloat)
+		writeUint64(out, math.Float64bits(obj.Value))
+	default:
+		return fmt.Errorf("constant of type %s can't be serialized", obj.Type())
+	}
+	return nil
+}
+
+func writeUint16(out *bytes.Buffer, v uint16) {
+	out.Write(binary.BigEndian.AppendUint16(nil, v))
+}
+
+func writeUint32(out *bytes.Buffer, v uint32) {
+	out.Write(binary.BigEndian.AppendUint32(nil, v))
+}
+
+func writeUint64(out *bytes.Buffer, v uint64) {
+	out.Write(binary.BigEndian.AppendUint64(nil, v))
+}
+
+// length prefixed byte slice
+func writeBytes(out *bytes.Buffer, b []byte) {
+	writeUint32(out, uint32(len(b)))
+	out.Write(b)
+}
+
+// reads values in order they were written. After first error all reads
+// return zero values and err is kept
+type reader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.offset+n > len(r.data) {
+		r.err = ErrTruncated
+		return nil
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b
+}
+
+func (r *reader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// copies data, so decoded bytecode doesn't keep whole file alive
+func (r *reader) bytes() []byte {
+	n := r.uint32()
+	b := r.next(int(n))
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func (r *reader) constant() (object.Object, error) {
+	tag := r.byte()
+
+	var obj object.Object
+	switch tag {
+	case tagInteger:
+		obj = &object.Integer{Value: int64(r.uint64())}
+	case tagString:
+		obj = &object.String{Value: string(r.bytes())}
+	case tagCompiledFunction:
+		fn := &object.CompiledFunction{}
+		fn.NumLocals = int(r.uint32())
+		fn.NumParameters = int(r.uint32())
+		fn.Instructions = r.bytes()
+		obj = fn
+	case tagFloat:
+		obj = &object.Float{Value: math.Float64frombits(r.uint64())}
+	default:
+		if r.err == nil {
+			return nil, fmt.Errorf("unknown constant tag %d", tag)
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return obj, nil
+}
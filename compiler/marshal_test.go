@@ -0,0 +1,177 @@
+package compiler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Savvelius/go-interp/code"
+	"github.com/Savvelius/go-interp/object"
+)
+
+func TestBytecodeMarshalRoundTrip(t *testing.T) {
+	input := `
+	let greet = fn(name) { "hello " + name };
+	let adder = fn(a) { fn(b) { a + b } };
+	greet("monkey");
+	adder(-1)(9223372036854775807);
+	adder(0.1)(-2.5e-10);
+	`
+
+	compiler := New()
+	err := compiler.Compile(parse(input))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	original := compiler.Bytecode()
+
+	data, err := original.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %s", err)
+	}
+
+	decoded := &Bytecode{}
+	err = decoded.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary failed: %s", err)
+	}
+
+	err = testInstructions([]code.Instructions{original.Instructions}, decoded.Instructions)
+	if err != nil {
+		t.Fatalf("instructions differ: %s", err)
+	}
+	if len(decoded.Constants) != len(original.Constants) {
+		t.Fatalf("wrong number of constants. got=%d, want=%d",
+			len(decoded.Constants), len(original.Constants))
+	}
+	if decoded.Disassemble() != original.Disassemble() {
+		t.Errorf("disassembly differs.\nwant=%s\ngot=%s",
+			original.Disassemble(), decoded.Disassemble())
+	}
+	for i, constant := range original.Constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			decodedFn := decoded.Constants[i].(*object.CompiledFunction)
+			if decodedFn.NumLocals != fn.NumLocals || decodedFn.NumParameters != fn.NumParameters {
+				t.Errorf("constant %d - wrong function metadata. got=%+v, want=%+v",
+					i, decodedFn, fn)
+			}
+		}
+	}
+}
+
+func TestBytecodeUnmarshalErrors(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse(`"a" + "b"`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	valid, err := compiler.Bytecode().MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %s", err)
+	}
+
+	corrupted := append([]byte{}, valid...)
+	corrupted[len(magic)+4] ^= 0xff
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"empty", []byte{}, ErrBadMagic},
+		{"wrong magic", []byte("ELF\x00\x00\x01"), ErrBadMagic},
+		{"corrupted", corrupted, ErrChecksumMismatch},
+		{"truncated", valid[:len(valid)-6], ErrChecksumMismatch},
+		{"too short", magic, ErrTruncated},
+	}
+	for _, tt := range tests {
+		err := (&Bytecode{}).UnmarshalBinary(tt.data)
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("%s: wrong error. got=%v, want=%v", tt.name, err, tt.expected)
+		}
+	}
+}
+
+func TestBytecodeUnmarshalInvalidInstructions(t *testing.T) {
+	fn := &object.CompiledFunction{
+		Instructions: concatInstructions([]code.Instructions{
+			code.Make(code.OpGetLocal, 1),
+			code.Make(code.OpReturnValue),
+		}),
+		NumLocals: 1,
+	}
+
+	tests := []struct {
+		name     string
+		bytecode *Bytecode
+		expected string
+	}{
+		{
+			"constant out of range",
+			&Bytecode{Instructions: code.Make(code.OpConstant, 5)},
+			"invalid bytecode: main: at 0: constant 5 out of range, pool has 0",
+		},
+		{
+			"truncated operand",
+			&Bytecode{Instructions: code.Instructions{byte(code.OpConstant)}},
+			"invalid bytecode: main: at 0: OpConstant operands are truncated",
+		},
+		{
+			"undefined opcode",
+			&Bytecode{Instructions: code.Instructions{255}},
+			"invalid bytecode: main: at 0: opcode 255 undefined",
+		},
+		{
+			"builtin out of range",
+			&Bytecode{Instructions: code.Make(code.OpGetBuiltin, 200)},
+			"invalid bytecode: main: at 0: builtin 200 out of range",
+		},
+		{
+			"closure of non-function",
+			&Bytecode{
+				Instructions: code.Make(code.OpClosure, 0, 0),
+				Constants:    []object.Object{&object.Integer{Value: 1}},
+			},
+			"invalid bytecode: main: at 0: closure of non-function constant 0",
+		},
+		{
+			"jump into instruction",
+			&Bytecode{Instructions: concatInstructions([]code.Instructions{
+				code.Make(code.OpJump, 4),
+				code.Make(code.OpConstant, 0),
+			}), Constants: []object.Object{&object.Integer{Value: 1}}},
+			"invalid bytecode: main: at 0: jump target 4 is not an instruction",
+		},
+		{
+			"local out of range in function",
+			&Bytecode{
+				Instructions: code.Make(code.OpClosure, 0, 0),
+				Constants:    []object.Object{fn},
+			},
+			"invalid bytecode: function constant 0: at 0: local 1 out of range, function has 1",
+		},
+	}
+
+	for _, tt := range tests {
+		data, err := tt.bytecode.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: MarshalBinary failed: %s", tt.name, err)
+		}
+
+		err = (&Bytecode{}).UnmarshalBinary(data)
+		if !errors.Is(err, ErrInvalidBytecode) {
+			t.Fatalf("%s: wrong error. got=%v, want=%v", tt.name, err, ErrInvalidBytecode)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%s: wrong message.\nwant=%q\ngot= %q", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestBytecodeMarshalUnsupportedConstant(t *testing.T) {
+	bytecode := &Bytecode{Constants: []object.Object{&object.Boolean{Value: true}}}
+
+	_, err := bytecode.MarshalBinary()
+	if err == nil {
+		t.Fatalf("expected error for boolean constant")
+	}
+}
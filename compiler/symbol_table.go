@@ -0,0 +1,120 @@
+package compiler
+
+import "sort"
+
+type SymbolScope string
+
+const (
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	BuiltinScope  SymbolScope = "BUILTIN"
+	FreeScope     SymbolScope = "FREE"     // captured from enclosing function
+	FunctionScope SymbolScope = "FUNCTION" // name of function that is being compiled
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+type SymbolTable struct {
+	Outer *SymbolTable // nil for global table
+
+	store          map[string]Symbol
+	numDefinitions int
+
+	FreeSymbols []Symbol // original symbols of enclosing scopes captured by this one
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		store:       map[string]Symbol{},
+		FreeSymbols: []Symbol{},
+	}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// binds name to the next free index of this table's scope
+func (s *SymbolTable) Define(name string) Symbol {
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+// builtins are indexed by their position in builtins list, not by definition order
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope}
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+// looks name up in this table and then in enclosing ones.
+// Locals of enclosing tables become free symbols of this one
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if ok || s.Outer == nil {
+		return symbol, ok
+	}
+
+	symbol, ok = s.Outer.Resolve(name)
+	if !ok {
+		return symbol, ok
+	}
+
+	if symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+		return symbol, ok
+	}
+
+	return s.defineFree(symbol), true
+}
+
+// copy of the table, defining names in it doesn't affect s. Enclosing tables are shared
+func (s *SymbolTable) Clone() *SymbolTable {
+	clone := &SymbolTable{
+		Outer:          s.Outer,
+		store:          make(map[string]Symbol, len(s.store)),
+		numDefinitions: s.numDefinitions,
+		FreeSymbols:    append([]Symbol{}, s.FreeSymbols...),
+	}
+	for name, symbol := range s.store {
+		clone.store[name] = symbol
+	}
+	return clone
+}
+
+// names defined in this table, not including enclosing ones, sorted
+func (s *SymbolTable) Names() []string {
+	names := make([]string, 0, len(s.store))
+	for name := range s.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
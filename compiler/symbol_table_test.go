@@ -0,0 +1,135 @@
+package compiler
+
+import "testing"
+
+func TestDefine(t *testing.T) {
+	expected := map[string]Symbol{
+		"a": {Name: "a", Scope: GlobalScope, Index: 0},
+		"b": {Name: "b", Scope: GlobalScope, Index: 1},
+		"c": {Name: "c", Scope: LocalScope, Index: 0},
+		"d": {Name: "d", Scope: LocalScope, Index: 1},
+		"e": {Name: "e", Scope: LocalScope, Index: 0},
+		"f": {Name: "f", Scope: LocalScope, Index: 1},
+	}
+
+	global := NewSymbolTable()
+	firstLocal := NewEnclosedSymbolTable(global)
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+
+	tests := []struct {
+		table *SymbolTable
+		name  string
+	}{
+		{global, "a"},
+		{global, "b"},
+		{firstLocal, "c"},
+		{firstLocal, "d"},
+		{secondLocal, "e"},
+		{secondLocal, "f"},
+	}
+	for _, tt := range tests {
+		if symbol := tt.table.Define(tt.name); symbol != expected[tt.name] {
+			t.Errorf("expected %s=%+v, got=%+v", tt.name, expected[tt.name], symbol)
+		}
+	}
+}
+
+func TestResolveGlobal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "b", Scope: GlobalScope, Index: 1},
+	}
+	for _, sym := range expected {
+		result, ok := global.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+}
+
+func TestResolveNestedLocal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.DefineBuiltin(0, "len")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("b")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("c")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "len", Scope: BuiltinScope, Index: 0},
+		{Name: "b", Scope: FreeScope, Index: 0},
+		{Name: "c", Scope: LocalScope, Index: 0},
+	}
+	for _, sym := range expected {
+		result, ok := secondLocal.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+
+	expectedFree := []Symbol{{Name: "b", Scope: LocalScope, Index: 0}}
+	if len(secondLocal.FreeSymbols) != len(expectedFree) {
+		t.Fatalf("wrong number of free symbols. got=%d, want=%d",
+			len(secondLocal.FreeSymbols), len(expectedFree))
+	}
+	for i, sym := range expectedFree {
+		if secondLocal.FreeSymbols[i] != sym {
+			t.Errorf("wrong free symbol. got=%+v, want=%+v", secondLocal.FreeSymbols[i], sym)
+		}
+	}
+
+	if _, ok := secondLocal.Resolve("d"); ok {
+		t.Errorf("name d resolved, but was never defined")
+	}
+}
+
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+
+	expected := Symbol{Name: "a", Scope: FunctionScope, Index: 0}
+
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
+func TestCloneAndNames(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("b")
+	global.DefineBuiltin(0, "len")
+
+	clone := global.Clone()
+	a := clone.Define("a")
+	if a.Index != 1 {
+		t.Errorf("clone should continue numbering. expected=1, got=%d", a.Index)
+	}
+	if _, ok := global.Resolve("a"); ok {
+		t.Errorf("symbol defined in clone is visible in original table")
+	}
+
+	names := clone.Names()
+	if len(names) != 3 || names[0] != "a" || names[1] != "b" || names[2] != "len" {
+		t.Errorf("wrong names. expected=[a b len], got=%v", names)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Savvelius/go-interp/compiler"
+)
+
+// disasm <file>: prints bytecode of compiled file or compiles source file first
+func disasm(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	var bytecode *compiler.Bytecode
+	var err error
+	if filepath.Ext(args[0]) == compiler.FileExtension {
+		bytecode, err = loadCompiledFile(args[0])
+	} else {
+		bytecode, err = compileFile(args[0])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Print(bytecode.Disassemble())
+	return 0
+}
@@ -1,10 +1,13 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
+	"math"
 
 	"github.com/Savvelius/go-interp/ast"
 	"github.com/Savvelius/go-interp/object"
+	"github.com/Savvelius/go-interp/token"
 )
 
 var (
@@ -13,7 +16,16 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
+// evaluates node, errors that don't have position yet are attributed to it
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	obj := eval(node, env)
+	if err, ok := obj.(*object.Error); ok && node != nil && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return obj
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node.Statements, env)
@@ -50,6 +62,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
+		if node.Operator == "and" || node.Operator == "or" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -76,7 +92,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return args[0]
 		}
 
-		return applyFunction(obj, args)
+		return applyFunction(obj, args, node.Pos())
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
@@ -94,17 +110,23 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		body := node.Body
 		params := node.Parameters
-		return &object.Function{Body: body, Parameters: params, Env: object.NewEnclosedEnvironment(env)}
+		return &object.Function{Name: node.Name, Body: body, Parameters: params, Env: object.NewEnclosedEnvironment(env)}
 
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+
 	case *ast.ArrayLiteral:
 		return evalArrayLiteral(node, env)
 
@@ -115,6 +137,22 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	return nil
 }
 
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out bytes.Buffer
+	for _, part := range node.Parts {
+		evaled := Eval(part, env)
+		if isError(evaled) {
+			return evaled
+		}
+		// function with empty body or ending in let evaluates to nil
+		if evaled == nil {
+			evaled = NULL
+		}
+		out.WriteString(object.Stringify(evaled))
+	}
+	return &object.String{Value: out.String()}
+}
+
 func evalArrayLiteral(node *ast.ArrayLiteral, env *object.Environment) object.Object {
 	objects := []object.Object{}
 
@@ -155,7 +193,8 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 	return &object.Hash{Pairs: hash}
 }
 
-func applyFunction(fn object.Object, arguments []object.Object) object.Object {
+// calls fn, errors raised inside of it get a stack frame with callSite appended
+func applyFunction(fn object.Object, arguments []object.Object, callSite token.Position) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
 		if len(fn.Parameters) != len(arguments) {
@@ -164,10 +203,17 @@ func applyFunction(fn object.Object, arguments []object.Object) object.Object {
 		}
 		extendedEnv := extendFunctionEnv(fn, arguments)
 		evaluated := Eval(fn.Body, extendedEnv)
+		if err, ok := evaluated.(*object.Error); ok {
+			err.Stack = append(err.Stack, object.StackFrame{Function: fn.Name, Pos: callSite})
+			return err
+		}
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
-		return fn.Fn(arguments...)
+		if result := fn.Fn(arguments...); result != nil {
+			return result
+		}
+		return NULL
 
 	default:
 		return newError("not a function: %s", fn.Type())
@@ -320,12 +366,29 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	}
 }
 
+// right operand is only evaluated if left one doesn't decide the result.
+// Result is the deciding operand itself, not a boolean
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	if node.Operator == "and" && !isTruthy(left) {
+		return left
+	}
+	if node.Operator == "or" && isTruthy(left) {
+		return left
+	}
+
+	return Eval(node.Right, env)
+}
+
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
-	case operator == "and":
-		return nativeBoolToBooleanObject(isTruthy(left) && isTruthy(right))
-	case operator == "or":
-		return nativeBoolToBooleanObject(isTruthy(left) || isTruthy(right))
+	case isFloatOperation(left, right):
+		return evalFloatInfixExpression(operator, left, right)
+
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 
@@ -372,10 +435,63 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 			return newError("division by zero")
 		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// at least one operand is float and the other is a number, integer gets promoted
+func isFloatOperation(left, right object.Object) bool {
+	_, leftOk := object.ToFloat(left)
+	_, rightOk := object.ToFloat(right)
+	return leftOk && rightOk && (left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ)
+}
+
+func evalFloatInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftVal, _ := object.ToFloat(left)
+	rightVal, _ := object.ToFloat(right)
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -399,12 +515,14 @@ func evalBangOperatorExpression(arg object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(arg object.Object) object.Object {
-	if arg.Type() != object.INTEGER_OBJ {
+	switch arg := arg.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -arg.Value}
+	case *object.Float:
+		return &object.Float{Value: -arg.Value}
+	default:
 		return newError("unknown operator: -%s", arg.Type())
 	}
-
-	value := arg.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
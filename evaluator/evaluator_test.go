@@ -28,6 +28,8 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"2 + 7 % 3 * 2", 4},
 	}
 
 	for _, tt := range tests {
@@ -36,6 +38,56 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5", 1.5},
+		{"-1.5", -1.5},
+		{"1.5 + 2.25", 3.75},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"7.5 % 2", 1.5},
+		{"1e3 - 1", 999},
+		{"-(2.5 * 2)", -5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		result, ok := evaluated.(*object.Float)
+		if !ok {
+			t.Errorf("%q: object is not Float. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if result.Value != tt.expected {
+			t.Errorf("%q: object has wrong value. got=%g, want=%g", tt.input, result.Value, tt.expected)
+		}
+	}
+}
+
+func TestNumericComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.5 > 1", true},
+		{"1 < 1.5", true},
+		{"2.0 == 2", true},
+		{"2 != 2.0", false},
+		{"0.1 + 0.2 == 0.3", false},
+		{"0x10 == 16", true},
+		{"0b11 + 0o7 == 1_0", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -60,6 +112,14 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1 < 2 and 3 < 4", true},
+		{"1 < 2 and 3 > 4", false},
+		{"1 > 2 or 3 <= 4", true},
 	}
 
 	for _, tt := range tests {
@@ -130,6 +190,14 @@ func TestErrorHandling(t *testing.T) {
 			"5 + true;",
 			"type mismatch: INTEGER + BOOLEAN",
 		},
+		{
+			"1.5 + true;",
+			"type mismatch: FLOAT + BOOLEAN",
+		},
+		{
+			"1.5 / 0",
+			"division by zero",
+		},
 		{
 			"5 + true; 5;",
 			"type mismatch: INTEGER + BOOLEAN",
@@ -165,6 +233,10 @@ func TestErrorHandling(t *testing.T) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			"5 % 0",
+			"division by zero",
+		},
 		{
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
@@ -189,6 +261,31 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+	}{
+		{"5 + true;", "1:1"},
+		{"let x = 1;\n  x - foobar", "2:7"},
+		{"let f = fn() {\n  1 / 0\n};\nf()", "2:3"},
+		{`[1, 2][0] + "a"`, "1:1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("input %q - wrong position. expected=%s, got=%s",
+				tt.input, tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -215,6 +312,12 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`len([1, 2, 3, true, "hehe"]) == 5`, true},
+		{`len("héllo")`, 6},
+		{`runeLen("héllo")`, 5},
+		{`runeLen("")`, 0},
+		{`runeLen("日本語 \u{1F600}")`, 5},
+		{`runeLen([1])`, "argument to `runeLen` must be STRING, got ARRAY"},
+		{`runeLen("a", "b")`, "wrong number of arguments. got=2, want=1"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -346,6 +449,35 @@ func TestStringConcat(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"plain ${"text"}"`, "plain text"},
+		{`let name = "monkey"; "hello ${name}!"`, "hello monkey!"},
+		{`let xs = [1, "a"]; "${len(xs)} items: ${xs}, ${xs[0] + 1}"`, `2 items: [1, "a"], 2`},
+		{`"${true} ${if (false) { 1 }} ${{"k": "v"}["k"]}"`, "true null v"},
+		{`let f = fn(x) { "<${x}>" }; "${f("${1 + 1}")}"`, "<2>"},
+		{`"escaped \${1} and ${"}"}"`, "escaped ${1} and }"},
+		{`"${fn(){}()}"`, "null"},
+		{`let f = fn() { let a = 1; }; "${f()}"`, "null"},
+	}
+
+	for _, tt := range tests {
+		testStringLiteral(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval(`"a ${1 + true}"`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "type mismatch: INTEGER + BOOLEAN" || errObj.Pos.String() != "1:6" {
+		t.Errorf("wrong error. got=%s", errObj.Inspect())
+	}
+}
+
 func TestStringCompare(t *testing.T) {
 	inputs := []struct {
 		input    string
@@ -519,7 +651,7 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 func TestBooleanOperators(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected bool
+		expected any
 	}{
 		{"!true", false},
 		{"!false", true},
@@ -529,13 +661,64 @@ func TestBooleanOperators(t *testing.T) {
 		{"!!5", true},
 
 		{"[1, 2, 3] and false", false},
-		{"[1, 2, 3] and false or {1: 3}", true},
+		{"[1, 2, 3] and false or {1: 3}", map[int64]int64{1: 3}},
 		{"true or false and 1", true},
 		{"false or false and 1", false},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-		testBooleanObject(t, evaluated, tt.expected)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case map[int64]int64:
+			hash, ok := evaluated.(*object.Hash)
+			if !ok {
+				t.Errorf("object is not Hash. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(hash.Pairs) != len(expected) {
+				t.Errorf("hash has wrong number of pairs. got=%d", len(hash.Pairs))
+			}
+			for key, value := range expected {
+				pair, ok := hash.Pairs[(&object.Integer{Value: key}).HashKey()]
+				if !ok {
+					t.Errorf("no pair for key %d", key)
+					continue
+				}
+				testIntegerObject(t, pair.Value, value)
+			}
+		}
+	}
+}
+
+func TestLogicalOperatorsShortCircuit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"1 and 2", 2},
+		{"0 and 2", 2},
+		{"1 or 2", 1},
+		{"false or 2", 2},
+		{"false and 2", false},
+		{"if (false) { 1 } or 3", 3},
+		{"[1, 2, 3] and false or 5", 5},
+		{"let x = 0; (x != 0) and (10 / x > 1)", false},
+		{"let x = 0; (x == 0) or (10 / x > 1)", true},
+		{"false and undefinedIdent", false},
+		{"true or undefinedIdent", true},
+		{"let x = 5; (x != 0) and (10 / x)", 2},
+		{"let x = 0; x != 0 and 10 / x > 1", false},
+		{"let x = 0; x == 0 or 10 / x > 1", true},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
 	}
 }
 
@@ -553,3 +736,52 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 
 	return true
 }
+
+func TestErrorStackTrace(t *testing.T) {
+	input := `let inner = fn(x) {
+  x + foo
+};
+let outer = fn(x) {
+  inner(x)
+};
+let f = fn() { outer(1) };
+f()`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: foo" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+	if errObj.Pos.String() != "2:7" {
+		t.Errorf("wrong error position. got=%s", errObj.Pos)
+	}
+
+	expected := []string{
+		"at inner (5:3)",
+		"at outer (7:16)",
+		"at f (8:1)",
+	}
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("wrong stack length. expected=%d, got=%d (%v)",
+			len(expected), len(errObj.Stack), errObj.Stack)
+	}
+	for i, frame := range errObj.Stack {
+		if frame.String() != expected[i] {
+			t.Errorf("wrong frame %d. expected=%q, got=%q", i, expected[i], frame.String())
+		}
+	}
+}
+
+func TestErrorStackTraceAnonymous(t *testing.T) {
+	evaluated := testEval("fn(x) { x / 0 }(1)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.StackTrace() != "\tat <anonymous> (1:1)\n" {
+		t.Errorf("wrong stack trace. got=%q", errObj.StackTrace())
+	}
+}
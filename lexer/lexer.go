@@ -1,12 +1,45 @@
 package lexer
 
-import "github.com/Savvelius/go-interp/token"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Savvelius/go-interp/token"
+)
 
 // Moves lexer one token up. Returns lexed token or EOF token on end of input
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhiteSpace()
+	for l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*') {
+		start := l.pos()
+		if !l.skipComment() {
+			// whole unterminated comment is reported as illegal
+			return token.Token{
+				Type:    token.ILLEGAL,
+				Literal: l.input[start.Offset-l.base : l.position],
+				Pos:     start,
+				End:     l.pos(),
+				Err:     &token.Error{Pos: start, Msg: "unterminated comment", Incomplete: true},
+			}
+		}
+		l.skipWhiteSpace()
+	}
+
+	start := l.pos()
+	tok := l.readToken()
+	tok.Pos = start
+	tok.End = l.pos()
+	if tok.Err != nil && !tok.Err.Pos.IsValid() {
+		tok.Err.Pos = start
+	}
+
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '*':
@@ -21,10 +54,24 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.LT_EQ, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.GT_EQ, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
 	case '-':
 		tok = newToken(token.MINUS, l.ch)
 	case '=':
@@ -36,8 +83,9 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok = l.readString()
+	case '`':
+		tok = l.readRawString()
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
@@ -67,26 +115,49 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookUpIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Type, tok.Literal = l.readNumber()
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
+			tok.Err = &token.Error{Msg: fmt.Sprintf("illegal character %q", tok.Literal)}
 		}
 	}
 	l.readChar()
 	return tok
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// any unicode letter, so identifiers can be written in any language
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+// rest of identifier may also have digits and combining marks, as in
+// Unicode XID_Continue, so scripts like Devanagari and decomposed
+// accents (e + U+0301) can be used
+func isIdentifierChar(ch rune) bool {
+	return isLetter(ch) || unicode.In(ch, unicode.Mn, unicode.Mc, unicode.Nd)
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func hexValue(ch rune) rune {
+	switch {
+	case isDigit(ch):
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
@@ -94,59 +165,327 @@ type Lexer struct {
 	input        string
 	position     int  // current index
 	readPosition int  // index of next token to be read
-	ch           byte // value at current index
+	ch           rune // character starting at current index
+
+	base   int // offset of input in the whole source
+	line   int // line of ch, starting at 1
+	column int // column of ch, starting at 1
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewAt(input, token.Position{Offset: 0, Line: 1, Column: 1})
+}
+
+// lexer for input that starts at pos of a bigger source, e.g. code embedded in a string
+func NewAt(input string, pos token.Position) *Lexer {
+	l := &Lexer{input: input, base: pos.Offset, line: pos.Line, column: pos.Column - 1}
 	l.readChar()
 	return l
 }
 
-func (l *Lexer) readNumber() string {
+// reads integer or float literal. Integers may have 0x, 0o or 0b prefix,
+// digits of any number may be separated by _. Literal is returned as written,
+// malformed ones like 0x or 1__0 are left for the parser to reject
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	start := l.position
-	for isDigit(l.ch) {
+
+	if l.ch == '0' && strings.ContainsRune("xXoObB", l.peekChar()) {
+		l.readChar()
+		l.readChar()
+		for isHexDigit(l.ch) || l.ch == '_' {
+			l.readChar()
+		}
+		return token.INT, l.input[start:l.position]
+	}
+
+	tokenType := token.TokenType(token.INT)
+	l.readDigits()
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+	if (l.ch == 'e' || l.ch == 'E') && (isDigit(l.peekChar()) || l.peekChar() == '+' || l.peekChar() == '-') {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+	return tokenType, l.input[start:l.position]
+}
+
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' {
 		l.readChar()
 	}
-	return l.input[start:l.position]
 }
 
 func (l *Lexer) readIdentifier() string {
 	start := l.position
-	for isLetter(l.ch) {
+	for isIdentifierChar(l.ch) {
 		l.readChar()
 	}
 	return l.input[start:l.position]
 }
 
-func (l *Lexer) readString() string {
-	start := l.position + 1 // token after "
+// reads "quoted" string, interpreting escape sequences. Current char is
+// the closing quote afterwards. Unterminated string is ILLEGAL token with
+// everything up to the end of input as literal, string with invalid escape
+// is ILLEGAL token with its whole source as literal and error at the escape.
+// String containing ${expr} is TEMPLATE token with its raw source as
+// literal, see SplitTemplate
+func (l *Lexer) readString() token.Token {
+	start := l.position
+	var value strings.Builder
+	var escapeErr *token.Error
+	template := false
+
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		switch l.ch {
+		case 0:
+			return unterminatedString(l.input[start:l.position])
+		case '"':
+			if escapeErr != nil {
+				return token.Token{Type: token.ILLEGAL, Literal: l.input[start : l.position+1], Err: escapeErr}
+			}
+			if template {
+				return token.Token{Type: token.TEMPLATE, Literal: l.input[start+1 : l.position]}
+			}
+			return token.Token{Type: token.STRING, Literal: value.String()}
+		case '\\':
+			escStart, escPos := l.position, l.pos()
+			r, ok := l.readEscape()
+			if !ok && escapeErr == nil {
+				escapeErr = &token.Error{
+					Pos: escPos,
+					Msg: fmt.Sprintf("invalid escape sequence %q in string", l.input[escStart:l.position+1]),
+				}
+			}
+			value.WriteRune(r)
+		case '$':
+			if l.peekChar() != '{' {
+				value.WriteRune(l.ch)
+				continue
+			}
+			template = true
+			l.readChar()
+			if !l.skipInterpolation() {
+				return unterminatedString(l.input[start:l.position])
+			}
+		default:
+			value.WriteRune(l.ch)
 		}
 	}
-	// current char is "
-	return l.input[start:l.position]
 }
 
-func (l *Lexer) peekChar() byte {
+func unterminatedString(lit string) token.Token {
+	return token.Token{
+		Type:    token.ILLEGAL,
+		Literal: lit,
+		Err:     &token.Error{Msg: "unterminated string", Incomplete: true},
+	}
+}
+
+// skips expression embedded in string, current char is the opening brace
+// before and the matching closing one after. Braces and strings inside
+// of the expression are skipped as a whole. Returns false at end of input
+func (l *Lexer) skipInterpolation() bool {
+	depth := 1
+	for {
+		l.readChar()
+		switch l.ch {
+		case 0:
+			return false
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return true
+			}
+		case '"':
+			if tok := l.readString(); tok.Err != nil && tok.Err.Incomplete {
+				return false
+			}
+		case '`':
+			if tok := l.readRawString(); tok.Err != nil {
+				return false
+			}
+		}
+	}
+}
+
+// piece of TEMPLATE token
+type TemplatePart struct {
+	Value  string         // decoded text, or source of embedded expression
+	IsExpr bool           // Value is an expression from ${}
+	Pos    token.Position // where Value starts
+	End    token.Position // right after the end of Value
+}
+
+// splits source of TEMPLATE token starting at pos into text and expressions
+func SplitTemplate(src string, pos token.Position) []TemplatePart {
+	l := NewAt(src, pos)
+	parts := []TemplatePart{}
+
+	var text strings.Builder
+	textPos := l.pos()
+	flushText := func() {
+		if text.Len() > 0 {
+			parts = append(parts, TemplatePart{Value: text.String(), Pos: textPos, End: l.pos()})
+			text.Reset()
+		}
+	}
+
+	for l.ch != 0 {
+		switch {
+		case l.ch == '$' && l.peekChar() == '{':
+			flushText()
+			l.readChar()
+			exprStart, exprPos := l.position+1, l.pos()
+			exprPos.Offset++
+			exprPos.Column++
+			l.skipInterpolation()
+			parts = append(parts, TemplatePart{Value: src[exprStart:l.position], IsExpr: true, Pos: exprPos, End: l.pos()})
+			l.readChar()
+			textPos = l.pos()
+		case l.ch == '\\':
+			r, _ := l.readEscape()
+			text.WriteRune(r)
+			l.readChar()
+		default:
+			text.WriteRune(l.ch)
+			l.readChar()
+		}
+	}
+	flushText()
+
+	return parts
+}
+
+// decodes escape sequence, current char is the backslash before and
+// the last char of the sequence after
+func (l *Lexer) readEscape() (rune, bool) {
+	switch l.peekChar() {
+	case 0:
+		return 0, false
+	case '"', '\\', '$':
+		l.readChar()
+		return l.ch, true
+	case 'n':
+		l.readChar()
+		return '\n', true
+	case 't':
+		l.readChar()
+		return '\t', true
+	case 'r':
+		l.readChar()
+		return '\r', true
+	case 'u':
+		// \u{1F600}: 1 to 6 hex digits of a code point
+		l.readChar()
+		if l.peekChar() != '{' {
+			return 0, false
+		}
+		l.readChar()
+		digits := 0
+		var r rune
+		for isHexDigit(l.peekChar()) && digits < 6 {
+			l.readChar()
+			r = r*16 + hexValue(l.ch)
+			digits++
+		}
+		if l.peekChar() != '}' {
+			return 0, false
+		}
+		l.readChar()
+		return r, digits > 0 && utf8.ValidRune(r)
+	default:
+		l.readChar()
+		return 0, false
+	}
+}
+
+// reads `raw` string: no escapes, may span lines. Unterminated one is
+// ILLEGAL token with everything up to the end of input as literal
+func (l *Lexer) readRawString() token.Token {
+	start := l.position
+	for {
+		l.readChar()
+		switch l.ch {
+		case 0:
+			return unterminatedString(l.input[start:l.position])
+		case '`':
+			return token.Token{Type: token.STRING, Literal: l.input[start+1 : l.position]}
+		}
+	}
+}
+
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return r
 }
 
 func (l *Lexer) readChar() {
+	// already at EOF, keep position stable
+	if l.readPosition > len(l.input) {
+		return
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	// invalid UTF-8 is read byte by byte as utf8.RuneError
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
+}
+
+// position of current character
+func (l *Lexer) pos() token.Position {
+	return token.Position{Offset: l.base + l.position, Line: l.line, Column: l.column}
+}
+
+// skips `// line` or `/* block */` comment starting at current char.
+// Returns false if block comment isn't closed before end of input
+func (l *Lexer) skipComment() bool {
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		return true
+	}
+
+	// block comments nest, so code containing them can be commented out
+	depth := 0
+	for l.ch != 0 {
+		if l.ch == '/' && l.peekChar() == '*' {
+			depth++
+			l.readChar()
+		} else if l.ch == '*' && l.peekChar() == '/' {
+			depth--
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				return true
+			}
+		}
+		l.readChar()
+	}
+	return false
 }
 
 func (l *Lexer) skipWhiteSpace() {
@@ -16,7 +16,7 @@ func TestNextToken(t *testing.T) {
 	};
 	
 	let result = add(five, ten);
-	!-/*5;
+	!-/ *5;
 	5 < 10 > 5;
 	
 	if (5 < 10) {
@@ -31,7 +31,8 @@ func TestNextToken(t *testing.T) {
 	"foo bar"
 	[1, 2];
 	{1: 2, "hello": true}
-	1 or 2 and 3`
+	1 or 2 and 3
+	1 <= 2 >= 3 % 4`
 	tests := []struct {
 		expectedType    token.TokenType
 		expectedLiteral string
@@ -140,6 +141,14 @@ func TestNextToken(t *testing.T) {
 		{token.AND, "and"},
 		{token.INT, "3"},
 
+		{token.INT, "1"},
+		{token.LT_EQ, "<="},
+		{token.INT, "2"},
+		{token.GT_EQ, ">="},
+		{token.INT, "3"},
+		{token.PERCENT, "%"},
+		{token.INT, "4"},
+
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -156,3 +165,315 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 10;\n  \"ab\" >= x"
+
+	tests := []struct {
+		expectedType token.TokenType
+		pos          token.Position
+		end          token.Position
+	}{
+		{token.LET, token.Position{Offset: 0, Line: 1, Column: 1}, token.Position{Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Offset: 4, Line: 1, Column: 5}, token.Position{Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Offset: 6, Line: 1, Column: 7}, token.Position{Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Offset: 8, Line: 1, Column: 9}, token.Position{Offset: 10, Line: 1, Column: 11}},
+		{token.SEMICOLON, token.Position{Offset: 10, Line: 1, Column: 11}, token.Position{Offset: 11, Line: 1, Column: 12}},
+		{token.STRING, token.Position{Offset: 14, Line: 2, Column: 3}, token.Position{Offset: 18, Line: 2, Column: 7}},
+		{token.GT_EQ, token.Position{Offset: 19, Line: 2, Column: 8}, token.Position{Offset: 21, Line: 2, Column: 10}},
+		{token.IDENT, token.Position{Offset: 22, Line: 2, Column: 11}, token.Position{Offset: 23, Line: 2, Column: 12}},
+		{token.EOF, token.Position{Offset: 23, Line: 2, Column: 12}, token.Position{Offset: 23, Line: 2, Column: 12}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos != tt.pos {
+			t.Errorf("tests[%d] - pos wrong. expected=%+v, got=%+v", i, tt.pos, tok.Pos)
+		}
+		if tok.End != tt.end {
+			t.Errorf("tests[%d] - end wrong. expected=%+v, got=%+v", i, tt.end, tok.End)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 5; // trailing comment
+/* block
+   comment */ x /* inline */ / 2
+/* outer /* nested */ still comment */ y
+a //
+/`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.IDENT, "y"},
+		{token.IDENT, "a"},
+		{token.SLASH, "/"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedPos     string
+	}{
+		{"x /* never closed", "/* never closed", "1:3"},
+		{"x\n/* outer /* inner */ ", "/* outer /* inner */ ", "2:1"},
+		{"/*/", "/*/", "1:1"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tt.input[0] == 'x' {
+			tok = l.NextToken()
+		}
+
+		if tok.Type != token.ILLEGAL {
+			t.Errorf("input %q - tokentype wrong. expected=%q, got=%q", tt.input, token.ILLEGAL, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %q - literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.String() != tt.expectedPos {
+			t.Errorf("input %q - pos wrong. expected=%s, got=%s", tt.input, tt.expectedPos, tok.Pos)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("input %q - expected EOF after unterminated comment, got=%q", tt.input, next.Type)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"plain"`, token.STRING, "plain"},
+		{`""`, token.STRING, ""},
+		{`"a\"b"`, token.STRING, `a"b`},
+		{`"back\\slash"`, token.STRING, `back\slash`},
+		{`"line\nbreak\ttab\rcr"`, token.STRING, "line\nbreak\ttab\rcr"},
+		{`"\u{41}\u{e9}\u{1F600}"`, token.STRING, "A\u00e9\U0001F600"},
+		{"\"two\nlines\"", token.STRING, "two\nlines"},
+		{"`raw \\n \"quoted\"`", token.STRING, `raw \n "quoted"`},
+		{"`multi\nline`", token.STRING, "multi\nline"},
+		{`"never closed`, token.ILLEGAL, `"never closed`},
+		{`"ends with escape\"`, token.ILLEGAL, `"ends with escape\"`},
+		{"`never closed", token.ILLEGAL, "`never closed"},
+		{`"bad \q escape"`, token.ILLEGAL, `"bad \q escape"`},
+		{`"\u{110000}"`, token.ILLEGAL, `"\u{110000}"`},
+		{`"\u{}"`, token.ILLEGAL, `"\u{}"`},
+		{`"\u41"`, token.ILLEGAL, `"\u41"`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Errorf("input %s - tokentype wrong. expected=%q, got=%q", tt.input, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %s - literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("input %s - expected EOF after string, got=%q %q", tt.input, next.Type, next.Literal)
+		}
+	}
+}
+
+func TestIllegalTokenErrors(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedMsg        string
+		expectedPos        string
+		expectedIncomplete bool
+	}{
+		{"1 # 2", `illegal character "#"`, "1:3", false},
+		{"x \\ 1", `illegal character "\\"`, "1:3", false},
+		{"/* never closed", "unterminated comment", "1:1", true},
+		{`"never closed`, "unterminated string", "1:1", true},
+		{"`never closed", "unterminated string", "1:1", true},
+		{`"a ${ "b" `, "unterminated string", "1:1", true},
+		{`"never \q closed`, "unterminated string", "1:1", true},
+		{`"bad \q escape"`, `invalid escape sequence "\\q" in string`, "1:6", false},
+		{"\"a\nb\\u41\"", `invalid escape sequence "\\u" in string`, "2:2", false},
+		{`"\x ${1} \y"`, `invalid escape sequence "\\x" in string`, "1:2", false},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		for tok.Type != token.ILLEGAL && tok.Type != token.EOF {
+			tok = l.NextToken()
+		}
+		if tok.Err == nil {
+			t.Fatalf("input %s - expected ILLEGAL token with error, got=%q", tt.input, tok.Type)
+		}
+		if tok.Err.Msg != tt.expectedMsg {
+			t.Errorf("input %s - msg wrong. expected=%q, got=%q", tt.input, tt.expectedMsg, tok.Err.Msg)
+		}
+		if tok.Err.Pos.String() != tt.expectedPos {
+			t.Errorf("input %s - pos wrong. expected=%s, got=%s", tt.input, tt.expectedPos, tok.Err.Pos)
+		}
+		if tok.Err.Incomplete != tt.expectedIncomplete {
+			t.Errorf("input %s - incomplete wrong. expected=%t, got=%t", tt.input, tt.expectedIncomplete, tok.Err.Incomplete)
+		}
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	input := "x = \"a\\t${b + 1}\\${c} ${ {1: 2}[1] }\""
+	l := New(input)
+	l.NextToken()
+	l.NextToken()
+	tok := l.NextToken()
+	if tok.Type != token.TEMPLATE {
+		t.Fatalf("tokentype wrong. expected=%q, got=%q", token.TEMPLATE, tok.Type)
+	}
+
+	start := tok.Pos
+	start.Offset++
+	start.Column++
+	parts := SplitTemplate(tok.Literal, start)
+
+	expected := []struct {
+		value  string
+		isExpr bool
+		pos    string
+	}{
+		{"a\t", false, "1:6"},
+		{"b + 1", true, "1:11"},
+		{"${c} ", false, "1:17"},
+		{" {1: 2}[1] ", true, "1:25"},
+	}
+	if len(parts) != len(expected) {
+		t.Fatalf("wrong number of parts. expected=%d, got=%d (%+v)", len(expected), len(parts), parts)
+	}
+	for i, part := range parts {
+		if part.Value != expected[i].value || part.IsExpr != expected[i].isExpr ||
+			part.Pos.String() != expected[i].pos {
+			t.Errorf("parts[%d] wrong. expected=%+v, got=%+v", i, expected[i], part)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := "0 42 1_000 0x1F 0o17 0b1010 3.14 1e-9 2.5E+3 1e3 007 1.foo 0x 5else"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0"},
+		{token.INT, "42"},
+		{token.INT, "1_000"},
+		{token.INT, "0x1F"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.FLOAT, "1e3"},
+		{token.INT, "007"},
+		// dot not followed by digit isn't part of the number
+		{token.INT, "1"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "foo"},
+		// malformed literals are rejected by the parser
+		{token.INT, "0x"},
+		{token.INT, "5"},
+		{token.ELSE, "else"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let имя = \"мир\";\nλ + _x日本 § ü\nनमस्ते e\u0301x1"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     string
+	}{
+		{token.LET, "let", "1:1"},
+		{token.IDENT, "имя", "1:5"},
+		{token.ASSIGN, "=", "1:9"},
+		{token.STRING, "мир", "1:11"},
+		{token.SEMICOLON, ";", "1:16"},
+		{token.IDENT, "λ", "2:1"},
+		{token.PLUS, "+", "2:3"},
+		{token.IDENT, "_x日本", "2:5"},
+		{token.ILLEGAL, "§", "2:10"},
+		{token.IDENT, "ü", "2:12"},
+		// combining marks and digits continue identifier
+		{token.IDENT, "नमस्ते", "3:1"},
+		{token.IDENT, "e\u0301x1", "3:8"},
+		{token.EOF, "", "3:12"},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.String() != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong. expected=%s, got=%s", i, tt.expectedPos, tok.Pos)
+		}
+	}
+
+	if end := len(input); l.pos().Offset != end {
+		t.Errorf("wrong offset at EOF. expected=%d, got=%d", end, l.pos().Offset)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
@@ -8,12 +9,54 @@ import (
 	"github.com/Savvelius/go-interp/repl"
 )
 
+const usage = `usage:
+	interp [--engine=eval|vm]             start interactive session with given engine, eval by default
+	interp run <file> [args...]           evaluate source file, args are available as ` + "`args`" + `
+	interp run -e <expr> [args...]        evaluate expression and print its result
+	interp build <file> [-o <out>]        compile source file to .monkeyc bytecode file
+	interp exec <file.monkeyc> [args...]  run compiled bytecode file, args are available as ` + "`args`" + `
+	interp disasm <file>                  print bytecode of source or .monkeyc file
+`
+
 func main() {
+	flags := flag.NewFlagSet("interp", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	engine := flags.String("engine", repl.EVAL_ENGINE, "engine executing REPL input")
+	flags.Parse(os.Args[1:])
+
+	if args := flags.Args(); len(args) > 0 {
+		// subcommands pick their engine themselves, run evaluates and exec uses the vm
+		engineSet := false
+		flags.Visit(func(f *flag.Flag) { engineSet = engineSet || f.Name == "engine" })
+		if engineSet {
+			fmt.Fprintf(os.Stderr, "--engine only applies to interactive session, not to %q\n%s", args[0], usage)
+			os.Exit(2)
+		}
+
+		switch args[0] {
+		case "run":
+			os.Exit(run(args[1:]))
+		case "build":
+			os.Exit(build(args[1:]))
+		case "exec":
+			os.Exit(execCompiled(args[1:]))
+		case "disasm":
+			os.Exit(disasm(args[1:]))
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\n%s", args[0], usage)
+			os.Exit(2)
+		}
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 	fmt.Printf("Hello, %v. This is the monkey programming language.\n", user.Username)
-	fmt.Printf("Feel free to type in any commands.\n")
-	repl.Start(os.Stdin, os.Stdout)
+	fmt.Printf("Feel free to type in any commands, .help lists REPL commands.\n")
+	err = repl.StartWithEngine(os.Stdin, os.Stdout, *engine)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 }
@@ -0,0 +1,83 @@
+package object
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Builtins are shared by evaluator and vm. Compiled code refers to builtins by their
+// index in this list, so new builtins must only be appended
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{
+		"len",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *String:
+				return &Integer{Value: int64(len(arg.Value))}
+			case *Array:
+				return &Integer{Value: int64(len(arg.Value))}
+			case *Hash:
+				return &Integer{Value: int64(len(arg.Pairs))}
+			default:
+				return newError("argument to `len` not supported, got %s", args[0].Type())
+			}
+		}},
+	},
+	{
+		"typeOf",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			return &String{Value: string(args[0].Type())}
+		}},
+	},
+	{
+		"print",
+		// returns nil, callers are expected to turn it into their null object
+		&Builtin{Fn: func(args ...Object) Object {
+			for _, arg := range args {
+				fmt.Print(arg.Inspect() + " ")
+			}
+			fmt.Print("\n")
+
+			return nil
+		}},
+	},
+	{
+		"runeLen",
+		// number of characters, while len counts bytes of strings
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			str, ok := args[0].(*String)
+			if !ok {
+				return newError("argument to `runeLen` must be STRING, got %s", args[0].Type())
+			}
+			return &Integer{Value: int64(utf8.RuneCountInString(str.Value))}
+		}},
+	},
+}
+
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+	return nil
+}
+
+func newError(format string, a ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
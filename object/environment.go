@@ -1,5 +1,7 @@
 package object
 
+import "sort"
+
 func NewEnvironment() *Environment {
 	return &Environment{store: map[string]Object{}, outer: nil}
 }
@@ -27,3 +29,13 @@ func (e *Environment) Set(name string, value Object) Object {
 	e.store[name] = value
 	return value
 }
+
+// names defined in this environment, not including outer ones, sorted
+func (e *Environment) Keys() []string {
+	keys := make([]string, 0, len(e.store))
+	for name := range e.store {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -4,15 +4,19 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"strconv"
 	"strings"
 
 	"github.com/Savvelius/go-interp/ast"
+	"github.com/Savvelius/go-interp/code"
+	"github.com/Savvelius/go-interp/token"
 )
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
@@ -22,6 +26,9 @@ const (
 	ARRAY_OBJ        = "ARRAY"
 	BUILTIN_OBJ      = "BUILTIN"
 	HASH_OBJ         = "HASH"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	CLOSURE_OBJ           = "CLOSURE"
 )
 
 type Object interface {
@@ -40,10 +47,40 @@ type Hashable interface {
 
 type Error struct {
 	Message string
+	Pos     token.Position // where the error was raised, invalid if unknown
+	Stack   []StackFrame   // calls the error unwound through, innermost first
+}
+
+// function call that was active when an error was raised
+type StackFrame struct {
+	Function string         // name of the called function, empty for anonymous ones
+	Pos      token.Position // call site
+}
+
+func (sf StackFrame) String() string {
+	name := sf.Function
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return fmt.Sprintf("at %s (%s)", name, sf.Pos)
+}
+
+// one line per stack frame, innermost first. Empty if error was raised at top level
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+	for _, frame := range e.Stack {
+		out.WriteString("\t" + frame.String() + "\n")
+	}
+	return out.String()
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR:" + e.Message }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR:" + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR:" + e.Message
+}
 
 type ReturnValue struct {
 	Value Object
@@ -62,6 +99,34 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+type Float struct {
+	Value float64
+}
+
+// shortest representation that reads back as the same value, always
+// with a decimal point or exponent so floats can be told from integers
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// value of integer or float as float64, used to promote mixed operands.
+// ok is false for non-numeric objects
+func ToFloat(obj Object) (value float64, ok bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *Float:
+		return obj.Value, true
+	default:
+		return 0, false
+	}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -98,6 +163,15 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// text of obj inside interpolated string: strings are inserted as is,
+// other objects as they are inspected
+func Stringify(obj Object) string {
+	if str, ok := obj.(*String); ok {
+		return str.Value
+	}
+	return obj.Inspect()
+}
+
 type Array struct {
 	Value []Object
 }
@@ -142,6 +216,7 @@ func (h *Hash) Inspect() string {
 }
 
 type Function struct {
+	Name       string // name it was bound to with let, empty for anonymous functions
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
@@ -171,3 +246,26 @@ type Builtin struct {
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
+
+// function body compiled to bytecode, lives in constant pool
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int // number of local bindings, including parameters
+	NumParameters int
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// CompiledFunction together with values of variables it captured
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
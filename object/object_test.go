@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +20,38 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestEnvironmentKeys(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("outer", &Integer{Value: 1})
+	env := NewEnclosedEnvironment(outer)
+	env.Set("b", &Integer{Value: 2})
+	env.Set("a", &Integer{Value: 3})
+
+	keys := env.Keys()
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("wrong keys. expected=[a b], got=%v", keys)
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{2, "2.0"},
+		{-0.5, "-0.5"},
+		{1e-9, "1e-09"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, tt := range tests {
+		got := (&Float{Value: tt.value}).Inspect()
+		if got != tt.expected {
+			t.Errorf("wrong Inspect for %v. want=%q, got=%q", tt.value, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/Savvelius/go-interp/token"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// single problem found while parsing
+type Error struct {
+	Pos      token.Position
+	Expected token.TokenType // empty if any token would be wrong here
+	Actual   token.TokenType // type of the offending token
+	Severity Severity
+	Msg      string // message without position
+}
+
+// "line:col: msg", as printed by the REPL
+func (e *Error) String() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+func (e *Error) Error() string { return e.String() }
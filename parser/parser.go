@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/Savvelius/go-interp/ast"
 	"github.com/Savvelius/go-interp/lexer"
@@ -18,33 +19,45 @@ type (
 const (
 	_ int = iota
 	LOWEST
+	LOGICAL_OR  // or
+	LOGICAL_AND // and
 	EQUALS      // == or !=
-	LESSGREATER // > or <
+	LESSGREATER // >, <, >= or <=
 	SUM         // + or -
-	PRODUCT     // * or /
+	PRODUCT     // *, / or %
 	PREFIX      // -X or !X
 	CALL        // function(X)
 	INDEX       // arr[1]
 )
 
 var precedences = map[token.TokenType]int{
+	token.OR:       LOGICAL_OR,
+	token.AND:      LOGICAL_AND,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
-	token.OR:       SUM,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
-	token.AND:      PRODUCT,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.PERCENT:  PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
 }
 
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []*Error
+
+	// set after an error until the parser synchronizes at a statement boundary,
+	// errors reported meanwhile are most likely caused by the first one
+	panicMode bool
+
+	braceDepth int // { minus } up to and including curToken
+	blockLevel int // braceDepth right after { of innermost block being parsed, 0 outside of blocks
 
 	curToken  token.Token
 	peekToken token.Token
@@ -62,14 +75,16 @@ func (p *Parser) registerInfix(tokenType token.TokenType, parseFn infixParseFn)
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []*Error{}}
 
 	p.prefixParseFns = map[token.TokenType]prefixParseFn{}
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.TRUE, p.parseTrueLiteral)
 	p.registerPrefix(token.FALSE, p.parseFalseLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE, p.parseInterpolatedString)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
@@ -77,6 +92,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 
 	p.infixParseFns = map[token.TokenType]infixParseFn{}
 	p.registerInfix(token.EQ, p.parseInfixExpression)
@@ -85,8 +101,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.AND, p.parseInfixExpression)
 	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
@@ -113,24 +132,90 @@ func (p *Parser) curPrecedence() int {
 	return LOWEST
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*Error {
 	return p.errors
 }
 
+// records err unless parser is already recovering from a previous one
+func (p *Parser) addError(err *Error) {
+	if p.panicMode {
+		return
+	}
+	p.errors = append(p.errors, err)
+	p.panicMode = true
+}
+
+// skips tokens until the end of a broken statement: cur is `;` or next token
+// is `}`, EOF, or starts a new statement. Braces opened meanwhile are skipped
+// as a whole. Stops at } that closes the enclosing block, without skipping it
+func (p *Parser) synchronize() {
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.RBRACE) && p.braceDepth < p.blockLevel {
+			break
+		}
+
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				p.panicMode = false
+				return
+			}
+		}
+
+		if depth == 0 && (p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) ||
+			p.peekTokenIs(token.LET) || p.peekTokenIs(token.RETURN)) {
+			break
+		}
+		p.nextToken()
+	}
+	p.panicMode = false
+}
+
 func (p *Parser) peekError(expectedType token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		expectedType, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(&Error{
+		Pos:      p.peekToken.Pos,
+		Expected: expectedType,
+		Actual:   p.peekToken.Type,
+		Severity: SeverityError,
+		Msg: fmt.Sprintf("expected next token to be %s, got %s instead",
+			expectedType, p.peekToken.Type),
+	})
 }
 
 func (p *Parser) noPrefixFnError(tok_type token.TokenType) {
-	msg := fmt.Sprintf("no prefix function for %s was found", tok_type)
-	p.errors = append(p.errors, msg)
+	p.addError(&Error{
+		Pos:      p.curToken.Pos,
+		Actual:   tok_type,
+		Severity: SeverityError,
+		Msg:      fmt.Sprintf("no prefix function for %s was found", tok_type),
+	})
 }
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.braceDepth++
+	case token.RBRACE:
+		p.braceDepth--
+	}
+}
+
+// skips to } matching { that brought braceDepth to level, so construct that
+// failed after opening a brace doesn't leave its } behind to be reported again
+func (p *Parser) skipToClosingBrace(level int) {
+	for !p.curTokenIs(token.EOF) && !(p.curTokenIs(token.RBRACE) && p.braceDepth < level) {
+		p.nextToken()
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -138,11 +223,12 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 	for p.curToken.Type != token.EOF {
 		stmt := p.parseStatement()
-		if stmt == nil {
-			panic("parsed statement is nil")
+		// statement that failed to parse is dropped
+		if p.panicMode {
+			p.synchronize()
+		} else if stmt != nil {
+			program.Statements = append(program.Statements, stmt)
 		}
-
-		program.Statements = append(program.Statements, stmt)
 		p.nextToken()
 	}
 
@@ -289,11 +375,13 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
-	return &ast.CallExpression{
+	call := &ast.CallExpression{
 		Token:     p.curToken,
 		Function:  function,
 		Arguments: p.parseCallArguments(),
 	}
+	call.Closing = p.curToken
+	return call
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
@@ -306,6 +394,7 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
+	idx.Closing = p.curToken
 	return idx
 }
 
@@ -333,22 +422,65 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 	return args
 }
 
+// reports what lexer couldn't make sense of, literal of illegal token is the offending source
+func (p *Parser) parseIllegal() ast.Expression {
+	lexErr := p.curToken.Err
+	if lexErr == nil {
+		lexErr = &token.Error{Pos: p.curToken.Pos, Msg: fmt.Sprintf("illegal character %q", p.curToken.Literal)}
+	}
+
+	p.addError(&Error{
+		Pos:      lexErr.Pos,
+		Actual:   token.ILLEGAL,
+		Severity: SeverityError,
+		Msg:      lexErr.Msg,
+	})
+	return nil
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
+	lit := p.curToken.Literal
+	// base 0 handles prefixes and _, but would read 010 as octal
+	if len(lit) > 1 && lit[0] == '0' && !strings.ContainsRune("xXoObB", rune(lit[1])) {
+		lit = strings.TrimLeft(lit, "0")
+		if lit == "" {
+			lit = "0"
+		}
+	}
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 10, 64)
+	value, err := strconv.ParseInt(lit, 0, 64)
 
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(&Error{
+			Pos:      p.curToken.Pos,
+			Actual:   p.curToken.Type,
+			Severity: SeverityError,
+			Msg:      fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+		})
 		return nil
 	}
 	return &ast.IntegerLiteral{Token: p.curToken, Value: value}
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+
+	if err != nil {
+		p.addError(&Error{
+			Pos:      p.curToken.Pos,
+			Actual:   p.curToken.Type,
+			Severity: SeverityError,
+			Msg:      fmt.Sprintf("could not parse %q as float", p.curToken.Literal),
+		})
+		return nil
+	}
+	return &ast.FloatLiteral{Token: p.curToken, Value: value}
+}
+
 func (p *Parser) parseTrueLiteral() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: true}
 }
@@ -361,6 +493,49 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// embedded expressions are parsed by their own parsers, positions stay relative to the whole source
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+
+	// source of the template starts right after the opening quote
+	start := p.curToken.Pos
+	start.Offset++
+	start.Column++
+
+	for _, part := range lexer.SplitTemplate(p.curToken.Literal, start) {
+		if !part.IsExpr {
+			str.Parts = append(str.Parts, &ast.StringLiteral{
+				Token: token.Token{Type: token.STRING, Literal: part.Value, Pos: part.Pos, End: part.End},
+				Value: part.Value,
+			})
+			continue
+		}
+
+		sub := New(lexer.NewAt(part.Value, part.Pos))
+		if sub.curTokenIs(token.EOF) {
+			p.addError(&Error{
+				Pos:      part.Pos,
+				Actual:   token.EOF,
+				Severity: SeverityError,
+				Msg:      "empty expression in string interpolation",
+			})
+			return nil
+		}
+
+		expr := sub.parseExpression(LOWEST)
+		if len(sub.errors) == 0 && !sub.peekTokenIs(token.EOF) {
+			sub.peekError(token.EOF)
+		}
+		if len(sub.errors) != 0 {
+			p.addError(sub.errors[0])
+			return nil
+		}
+		str.Parts = append(str.Parts, expr)
+	}
+
+	return str
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
@@ -387,6 +562,7 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 
 		return args
 	}(p)
+	array.Closing = p.curToken
 
 	return array
 }
@@ -397,11 +573,13 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		Token: p.curToken,
 		Pairs: map[ast.Expression]ast.Expression{},
 	}
+	level := p.braceDepth
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 		key := p.parseExpression(LOWEST)
 		if !p.expectPeek(token.COLON) {
+			p.skipToClosingBrace(level)
 			return nil
 		}
 		p.nextToken()
@@ -411,6 +589,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		// if } is next => do not move token up. If it isn't =>
 		// move one token up, if token isn't ',' => error
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			p.skipToClosingBrace(level)
 			return nil
 		}
 	}
@@ -418,15 +597,20 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	hash.Closing = p.curToken
 
 	return hash
 }
 
 func (p *Parser) parseStatement() ast.Statement {
 
+	// nil pointers are returned as untyped nil, so callers can compare with nil
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
 		return p.parseReturnStatement()
 	// case token.LBRACE:
@@ -441,15 +625,35 @@ func (p *Parser) parseBlockStatement() ast.Statement {
 		Token:      p.curToken,
 		Statements: []ast.Statement{},
 	}
+	outerLevel := p.blockLevel
+	p.blockLevel = p.braceDepth
+	defer func() { p.blockLevel = outerLevel }()
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		subStmt := p.parseStatement()
-		if subStmt != nil {
+		// block recovers by itself, so errors don't leak into enclosing statement
+		if p.panicMode {
+			p.synchronize()
+			// stopped at closing } of this block
+			if p.curTokenIs(token.RBRACE) && p.braceDepth < p.blockLevel {
+				continue
+			}
+		} else if subStmt != nil {
 			block.Statements = append(block.Statements, subStmt)
 		}
 		p.nextToken()
 	}
+	if p.curTokenIs(token.EOF) {
+		p.addError(&Error{
+			Pos:      p.curToken.Pos,
+			Expected: token.RBRACE,
+			Actual:   token.EOF,
+			Severity: SeverityError,
+			Msg:      "expected next token to be }, got EOF instead",
+		})
+	}
+	block.Closing = p.curToken
 	return block
 }
 
@@ -488,6 +692,11 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
+	// lets function refer to itself by name
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fl.Name = stmt.Name.Value
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -169,6 +169,56 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"1_000_000", int64(1000000)},
+		{"0xff", int64(255)},
+		{"0XFF", int64(255)},
+		{"0o17", int64(15)},
+		{"0b1010", int64(10)},
+		{"0x_ff", int64(255)},
+		{"010", int64(10)},
+		{"00", int64(0)},
+		{"3.14", 3.14},
+		{"1_000.5", 1000.5},
+		{"1e-9", 1e-9},
+		{"2.5E+3", 2500.0},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		switch expected := tt.expected.(type) {
+		case int64:
+			literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+			if !ok {
+				t.Fatalf("%q: expression is not IntegerLiteral. got=%T", tt.input, stmt.Expression)
+			}
+			if literal.Value != expected {
+				t.Errorf("%q: wrong value. expected=%d, got=%d", tt.input, expected, literal.Value)
+			}
+		case float64:
+			literal, ok := stmt.Expression.(*ast.FloatLiteral)
+			if !ok {
+				t.Fatalf("%q: expression is not FloatLiteral. got=%T", tt.input, stmt.Expression)
+			}
+			if literal.Value != expected {
+				t.Errorf("%q: wrong value. expected=%g, got=%g", tt.input, expected, literal.Value)
+			}
+		}
+		if stmt.Expression.String() != tt.input {
+			t.Errorf("%q: String() wrong. got=%q", tt.input, stmt.Expression.String())
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input        string
@@ -382,6 +432,30 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"1 or 2 and 3 or true",
 			"((1 or (2 and 3)) or true)",
 		},
+		{
+			"a < b and c < d",
+			"((a < b) and (c < d))",
+		},
+		{
+			"a == b or c != d and e",
+			"((a == b) or ((c != d) and e))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"x != 0 and 10 / x > 1",
+			"((x != 0) and ((10 / x) > 1))",
+		},
+		{
+			"!a and -b or c",
+			"(((!a) and (-b)) or c)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -397,6 +471,48 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+// every pair of binary operators is checked against expected precedence levels,
+// all operators are left associative
+func TestOperatorPrecedenceMatrix(t *testing.T) {
+	levels := map[string]int{
+		"or":  LOGICAL_OR,
+		"and": LOGICAL_AND,
+		"==":  EQUALS,
+		"!=":  EQUALS,
+		"<":   LESSGREATER,
+		">":   LESSGREATER,
+		"<=":  LESSGREATER,
+		">=":  LESSGREATER,
+		"+":   SUM,
+		"-":   SUM,
+		"*":   PRODUCT,
+		"/":   PRODUCT,
+		"%":   PRODUCT,
+	}
+
+	for first, firstLevel := range levels {
+		for second, secondLevel := range levels {
+			input := fmt.Sprintf("a %s b %s c", first, second)
+
+			var expected string
+			if firstLevel >= secondLevel {
+				expected = fmt.Sprintf("((a %s b) %s c)", first, second)
+			} else {
+				expected = fmt.Sprintf("(a %s (b %s c))", first, second)
+			}
+
+			l := lexer.New(input)
+			p := New(l)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			if actual := program.String(); actual != expected {
+				t.Errorf("%q: expected=%q, got=%q", input, expected, actual)
+			}
+		}
+	}
+}
+
 func TestBooleanLiteral(t *testing.T) {
 	inputs := []struct {
 		input    string
@@ -600,6 +716,33 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestFunctionLiteralWithName(t *testing.T) {
+	input := `let myFunction = fn() { };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+			len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T",
+			program.Statements[0])
+	}
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+	if function.Name != "myFunction" {
+		t.Fatalf("function literal name wrong. want 'myFunction', got=%q",
+			function.Name)
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 	l := lexer.New(input)
@@ -912,3 +1055,240 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestNodePositions(t *testing.T) {
+	input := "let x = add(1, 2);\nfoo[1 + 2];\nif (x) { [x, {1: 2}] }"
+
+	tests := []struct {
+		stmt     int
+		expected string // start and end of statement as line:col-line:col
+	}{
+		{0, "1:1-1:18"},
+		{1, "2:1-2:11"},
+		{2, "3:1-3:23"},
+	}
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	for _, tt := range tests {
+		stmt := program.Statements[tt.stmt]
+		got := fmt.Sprintf("%s-%s", stmt.Pos(), stmt.End())
+		if got != tt.expected {
+			t.Errorf("statement %d (%s) - wrong span. expected=%s, got=%s",
+				tt.stmt, stmt.String(), tt.expected, got)
+		}
+	}
+
+	index := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.IndexExpression)
+	infix := index.Index.(*ast.InfixExpression)
+	if infix.Pos().String() != "2:5" || infix.End().String() != "2:10" {
+		t.Errorf("infix expression - wrong span. got=%s-%s", infix.Pos(), infix.End())
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedPos      string
+		expectedExpected token.TokenType
+		expectedActual   token.TokenType
+		expectedString   string
+	}{
+		{"let = 5;", "1:5", token.IDENT, token.ASSIGN,
+			"1:5: expected next token to be IDENT, got = instead"},
+		{"add(1, 2", "1:9", token.RPAREN, token.EOF,
+			"1:9: expected next token to be ), got EOF instead"},
+		{"let x = 5;\n  ;", "2:3", "", token.SEMICOLON,
+			"2:3: no prefix function for ; was found"},
+		{"99999999999999999999", "1:1", "", token.INT,
+			"1:1: could not parse \"99999999999999999999\" as integer"},
+		{"0x", "1:1", "", token.INT,
+			"1:1: could not parse \"0x\" as integer"},
+		{"1__0", "1:1", "", token.INT,
+			"1:1: could not parse \"1__0\" as integer"},
+		{"0b102", "1:1", "", token.INT,
+			"1:1: could not parse \"0b102\" as integer"},
+		{"1e+", "1:1", "", token.FLOAT,
+			"1:1: could not parse \"1e+\" as float"},
+		{"1_.5", "1:1", "", token.FLOAT,
+			"1:1: could not parse \"1_.5\" as float"},
+		{"let x = 1 + /* oops", "1:13", "", token.ILLEGAL,
+			"1:13: unterminated comment"},
+		{"let x = #;", "1:9", "", token.ILLEGAL,
+			"1:9: illegal character \"#\""},
+		{"let s = \"abc", "1:9", "", token.ILLEGAL,
+			"1:9: unterminated string"},
+		{"let s = `abc", "1:9", "", token.ILLEGAL,
+			"1:9: unterminated string"},
+		{"let s = \"a ${}\";", "1:14", "", token.EOF,
+			"1:14: empty expression in string interpolation"},
+		{"let s = \"a ${1 +}\";", "1:17", "", token.EOF,
+			"1:17: no prefix function for EOF was found"},
+		{"let s = \"a ${1 2}\";", "1:16", token.EOF, token.INT,
+			"1:16: expected next token to be EOF, got INT instead"},
+		{"let s = \"a\\qb\";", "1:11", "", token.ILLEGAL,
+			"1:11: invalid escape sequence \"\\\\q\" in string"},
+		{"let x = \\ 1;", "1:9", "", token.ILLEGAL,
+			"1:9: illegal character \"\\\\\""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("input %q - expected parser errors", tt.input)
+			continue
+		}
+
+		err := errors[0]
+		if err.Pos.String() != tt.expectedPos {
+			t.Errorf("input %q - wrong Pos. expected=%s, got=%s", tt.input, tt.expectedPos, err.Pos)
+		}
+		if err.Expected != tt.expectedExpected {
+			t.Errorf("input %q - wrong Expected. expected=%q, got=%q", tt.input, tt.expectedExpected, err.Expected)
+		}
+		if err.Actual != tt.expectedActual {
+			t.Errorf("input %q - wrong Actual. expected=%q, got=%q", tt.input, tt.expectedActual, err.Actual)
+		}
+		if err.Severity != SeverityError {
+			t.Errorf("input %q - wrong Severity. got=%s", tt.input, err.Severity)
+		}
+		if err.String() != tt.expectedString {
+			t.Errorf("input %q - wrong String(). expected=%q, got=%q", tt.input, tt.expectedString, err.String())
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements []string
+	}{
+		{
+			"let = 5; let y = 10;",
+			[]string{"1:5: expected next token to be IDENT, got = instead"},
+			[]string{"let y = 10;"},
+		},
+		{
+			"let x 5 * 2 + 3\nlet y = ;\nreturn y;",
+			[]string{
+				"1:7: expected next token to be =, got INT instead",
+				"2:9: no prefix function for ; was found",
+			},
+			[]string{"return y;"},
+		},
+		{
+			"add(1, 2\nlet z = 1;",
+			[]string{"2:1: expected next token to be ), got LET instead"},
+			[]string{"let z = 1;"},
+		},
+		{
+			"fn(x) { let = 1; x }; 5",
+			[]string{"1:13: expected next token to be IDENT, got = instead"},
+			[]string{"fn(x)x", "5"},
+		},
+		{
+			"if (x { 1 } ); 7",
+			[]string{"1:7: expected next token to be ), got { instead"},
+			[]string{"7"},
+		},
+		{
+			"{1: 2, 3}",
+			[]string{"1:9: expected next token to be :, got } instead"},
+			[]string{},
+		},
+		{
+			"let h = {\"a\" 1}; h",
+			[]string{"1:14: expected next token to be :, got INT instead"},
+			[]string{"h"},
+		},
+		{
+			"let f = fn() { {1 2}; 3 }; f",
+			[]string{"1:19: expected next token to be :, got INT instead"},
+			[]string{"let f = fn()3;", "f"},
+		},
+		{
+			"let f = fn() { 1 + }\nlet y = 2;\ny",
+			[]string{"1:20: no prefix function for } was found"},
+			[]string{"let f = fn();", "let y = 2;", "y"},
+		},
+		{
+			"let f = fn() { 1",
+			[]string{"1:17: expected next token to be }, got EOF instead"},
+			[]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("input %q - wrong number of errors. expected=%d, got=%d (%v)",
+				tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.String() != tt.expectedErrors[i] {
+				t.Errorf("input %q - wrong error %d. expected=%q, got=%q",
+					tt.input, i, tt.expectedErrors[i], err.String())
+			}
+		}
+
+		if len(program.Statements) != len(tt.expectedStatements) {
+			t.Errorf("input %q - wrong number of statements. expected=%d, got=%d",
+				tt.input, len(tt.expectedStatements), len(program.Statements))
+			continue
+		}
+		for i, stmt := range program.Statements {
+			if stmt == nil {
+				t.Fatalf("input %q - statement %d is nil", tt.input, i)
+			}
+			if stmt.String() != tt.expectedStatements[i] {
+				t.Errorf("input %q - wrong statement %d. expected=%q, got=%q",
+					tt.input, i, tt.expectedStatements[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestInterpolatedString(t *testing.T) {
+	input := `"hello ${name}, you have ${len(xs) + 1} items"`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+	if len(str.Parts) != 5 {
+		t.Fatalf("wrong number of parts. expected=5, got=%d", len(str.Parts))
+	}
+
+	expected := []string{"hello ", "name", ", you have ", "(len(xs) + 1)", " items"}
+	for i, part := range str.Parts {
+		if part.String() != expected[i] {
+			t.Errorf("parts[%d] wrong. expected=%q, got=%q", i, expected[i], part.String())
+		}
+	}
+	testIdentifier(t, str.Parts[1], "name")
+	if str.Parts[1].Pos().String() != "1:10" {
+		t.Errorf("wrong position of embedded expression. got=%s", str.Parts[1].Pos())
+	}
+	if str.String() != `"hello ${name}, you have ${(len(xs) + 1)} items"` {
+		t.Errorf("str.String() wrong. got=%q", str.String())
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Savvelius/go-interp/ast"
+	"github.com/Savvelius/go-interp/compiler"
+	"github.com/Savvelius/go-interp/lexer"
+	"github.com/Savvelius/go-interp/object"
+	"github.com/Savvelius/go-interp/parser"
+)
+
+// parses src, all parse errors are reported in returned error
+func parseSource(name, src string) (*ast.Program, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		msgs := make([]string, len(p.Errors()))
+		for i, err := range p.Errors() {
+			msgs[i] = err.String()
+		}
+		return nil, fmt.Errorf("%s: parse errors:\n\t%s", name, strings.Join(msgs, "\n\t"))
+	}
+	return program, nil
+}
+
+// command line arguments of a script, bound to `args`
+func argsArray(scriptArgs []string) *object.Array {
+	elements := make([]object.Object, len(scriptArgs))
+	for i, arg := range scriptArgs {
+		elements[i] = &object.String{Value: arg}
+	}
+	return &object.Array{Value: elements}
+}
+
+// index of `args` global in compiled programs, compileFile defines it before
+// anything else and execCompiled sets it before running
+const argsGlobal = 0
+
+// compiles source file, program can refer to `args`
+func compileFile(path string) (*compiler.Bytecode, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := parseSource(path, string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+	symbolTable.Define("args")
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	err = comp.Compile(program)
+	if err != nil {
+		return nil, fmt.Errorf("%s: compile error: %s", path, err)
+	}
+
+	return comp.Bytecode(), nil
+}
+
+// reads and validates .monkeyc file
+func loadCompiledFile(path string) (*compiler.Bytecode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bytecode := &compiler.Bytecode{}
+	err = bytecode.UnmarshalBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return bytecode, nil
+}
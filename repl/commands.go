@@ -0,0 +1,162 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Savvelius/go-interp/lexer"
+	"github.com/Savvelius/go-interp/object"
+	"github.com/Savvelius/go-interp/parser"
+	"github.com/Savvelius/go-interp/token"
+)
+
+// meta command, typed as .name arg
+type command struct {
+	name string
+	arg  string // name of the argument for help, empty if command takes none
+	help string
+	run  func(s *session, arg string)
+}
+
+// filled in init, .help refers to it
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"help", "", "show this help", (*session).help},
+		{"env", "", "list bindings of the environment", (*session).listEnv},
+		{"type", "expr", "evaluate expr and print type of the result", (*session).typeOf},
+		{"ast", "expr", "print parsed expr", (*session).printAst},
+		{"tokens", "expr", "print tokens of expr", (*session).printTokens},
+		{"load", "file", "evaluate file in the session", (*session).load},
+		{"save", "file", "write inputs of the session to file", (*session).save},
+		{"reset", "", "clear the environment and inputs of the session", (*session).reset},
+		{"engine", "", "print engine, or switch to `.engine eval|vm` and reset", (*session).switchEngine},
+		{"quit", "", "exit the REPL", (*session).quit},
+	}
+}
+
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func isCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), ".")
+}
+
+// runs meta command line like `.type 1 + 2`
+func (s *session) runCommand(line string) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line)[1:], " ")
+	arg = strings.TrimSpace(arg)
+
+	cmd := lookupCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(s.out, "unknown command .%s, type .help for list of commands\n", name)
+		return
+	}
+	if cmd.arg != "" && arg == "" {
+		fmt.Fprintf(s.out, "usage: .%s <%s>\n", cmd.name, cmd.arg)
+		return
+	}
+	cmd.run(s, arg)
+}
+
+func (s *session) help(string) {
+	for _, cmd := range commands {
+		usage := "." + cmd.name
+		if cmd.arg != "" {
+			usage += " <" + cmd.arg + ">"
+		}
+		fmt.Fprintf(s.out, "%-16s %s\n", usage, cmd.help)
+	}
+}
+
+func (s *session) listEnv(string) {
+	for _, name := range s.engine.names() {
+		val, ok := s.engine.get(name)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(s.out, "%s = %s\n", name, val.Inspect())
+	}
+}
+
+func (s *session) typeOf(src string) {
+	evaluated := s.evalSource(src)
+	if evaluated == nil {
+		return
+	}
+	if err, ok := evaluated.(*object.Error); ok {
+		s.printResult(err)
+		return
+	}
+	fmt.Fprintln(s.out, evaluated.Type())
+}
+
+func (s *session) printAst(src string) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseErrors(s.out, p.Errors())
+		return
+	}
+	fmt.Fprintln(s.out, program.String())
+}
+
+func (s *session) printTokens(src string) {
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(s.out, "%-6s %-10s %q\n", tok.Pos, tok.Type, tok.Literal)
+	}
+}
+
+func (s *session) load(path string) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+	s.printResult(s.evalSource(string(src)))
+}
+
+func (s *session) save(path string) {
+	transcript := strings.Join(s.inputs, "\n")
+	if transcript != "" {
+		transcript += "\n"
+	}
+	err := os.WriteFile(path, []byte(transcript), 0644)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+	}
+}
+
+func (s *session) reset(string) {
+	s.engine, _ = newEngine(s.engineName)
+	s.inputs = nil
+}
+
+// prints current engine or switches to named one, starting over like .reset
+func (s *session) switchEngine(name string) {
+	if name == "" {
+		fmt.Fprintln(s.out, s.engineName)
+		return
+	}
+
+	e, err := newEngine(name)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+	s.engine, s.engineName = e, name
+	s.inputs = nil
+}
+
+func (s *session) quit(string) {
+	s.done = true
+}
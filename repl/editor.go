@@ -0,0 +1,333 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// returned by readLine when user pressed Ctrl-C, the line is discarded
+var errInterrupted = errors.New("interrupted")
+
+// key codes
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// single line editor for terminal in raw mode: cursor movement, history and
+// its reverse search, tab completion
+type editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  *history
+	complete func(prefix string) []string // sorted candidates starting with prefix, nil disables completion
+
+	// state of the line being edited
+	prompt  string
+	buf     []rune
+	cursor  int    // index into buf
+	histIdx int    // entry shown from history, len(entries) for the new line
+	stashed string // new line, kept while browsing history
+}
+
+func newEditor(in io.Reader, out io.Writer, h *history, complete func(string) []string) *editor {
+	return &editor{in: bufio.NewReader(in), out: out, history: h, complete: complete}
+}
+
+func (e *editor) readLine(prompt string) (string, error) {
+	e.prompt, e.buf, e.cursor = prompt, nil, 0
+	e.histIdx, e.stashed = len(e.history.entries), ""
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyCR, keyLF:
+			return e.submit(), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+			e.delete()
+		case keyCtrlA:
+			e.cursor = 0
+		case keyCtrlE:
+			e.cursor = len(e.buf)
+		case keyCtrlB:
+			e.left()
+		case keyCtrlF:
+			e.right()
+		case keyCtrlK:
+			e.buf = e.buf[:e.cursor]
+		case keyCtrlU:
+			e.buf = e.buf[e.cursor:]
+			e.cursor = 0
+		case keyCtrlP:
+			e.historyPrev()
+		case keyCtrlN:
+			e.historyNext()
+		case keyCtrlR:
+			if e.search() {
+				return e.submit(), nil
+			}
+		case keyTab:
+			e.completeWord()
+		case keyBackspace, keyCtrlH:
+			if e.cursor > 0 {
+				e.left()
+				e.delete()
+			}
+		case keyEscape:
+			e.escapeSequence()
+		default:
+			if unicode.IsPrint(r) {
+				e.insert([]rune{r})
+			}
+		}
+		e.refresh()
+	}
+}
+
+// finishes editing, line is added to history
+func (e *editor) submit() string {
+	fmt.Fprint(e.out, "\n")
+	line := string(e.buf)
+	e.history.add(line)
+	return line
+}
+
+// handles ESC [ x and ESC O x sequences sent by arrows, home, end and delete keys
+func (e *editor) escapeSequence() {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return
+	}
+
+	var param []rune
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return
+		}
+		if !unicode.IsDigit(r) && r != ';' {
+			break
+		}
+		param = append(param, r)
+	}
+
+	switch r {
+	case 'A':
+		e.historyPrev()
+	case 'B':
+		e.historyNext()
+	case 'C':
+		e.right()
+	case 'D':
+		e.left()
+	case 'H':
+		e.cursor = 0
+	case 'F':
+		e.cursor = len(e.buf)
+	case '~':
+		switch string(param) {
+		case "1", "7":
+			e.cursor = 0
+		case "4", "8":
+			e.cursor = len(e.buf)
+		case "3":
+			e.delete()
+		}
+	}
+}
+
+func (e *editor) insert(runes []rune) {
+	buf := make([]rune, 0, len(e.buf)+len(runes))
+	buf = append(buf, e.buf[:e.cursor]...)
+	buf = append(buf, runes...)
+	e.buf = append(buf, e.buf[e.cursor:]...)
+	e.cursor += len(runes)
+}
+
+// removes rune under the cursor
+func (e *editor) delete() {
+	if e.cursor < len(e.buf) {
+		e.buf = append(e.buf[:e.cursor], e.buf[e.cursor+1:]...)
+	}
+}
+
+func (e *editor) left() {
+	if e.cursor > 0 {
+		e.cursor--
+	}
+}
+
+func (e *editor) right() {
+	if e.cursor < len(e.buf) {
+		e.cursor++
+	}
+}
+
+func (e *editor) setLine(line string) {
+	e.buf = []rune(line)
+	e.cursor = len(e.buf)
+}
+
+func (e *editor) historyPrev() {
+	if e.histIdx == 0 {
+		return
+	}
+	if e.histIdx == len(e.history.entries) {
+		e.stashed = string(e.buf)
+	}
+	e.histIdx--
+	e.setLine(e.history.entries[e.histIdx])
+}
+
+func (e *editor) historyNext() {
+	if e.histIdx == len(e.history.entries) {
+		return
+	}
+	e.histIdx++
+	if e.histIdx == len(e.history.entries) {
+		e.setLine(e.stashed)
+	} else {
+		e.setLine(e.history.entries[e.histIdx])
+	}
+}
+
+// reverse incremental search through history. Found entry replaces the line,
+// returns true if it should be submitted right away (Enter was pressed)
+func (e *editor) search() bool {
+	original, originalCursor := e.buf, e.cursor
+	var query []rune
+	match := len(e.history.entries) // index of matched entry, len(entries) if none
+
+	// finds newest entry before `from` containing query
+	find := func(from int) {
+		if from > len(e.history.entries) {
+			from = len(e.history.entries)
+		}
+		for i := from - 1; i >= 0; i-- {
+			if strings.Contains(e.history.entries[i], string(query)) {
+				match = i
+				e.setLine(e.history.entries[i])
+				return
+			}
+		}
+	}
+
+	for {
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), string(e.buf))
+
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return false
+		}
+
+		switch {
+		case r == keyCR || r == keyLF:
+			return true
+		case r == keyCtrlR:
+			find(match)
+		case r == keyBackspace || r == keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(e.history.entries))
+			}
+		case r == keyCtrlG || r == keyCtrlC:
+			e.buf, e.cursor = original, originalCursor
+			return false
+		case unicode.IsPrint(r):
+			query = append(query, r)
+			find(match + 1)
+		default:
+			// any other key ends search keeping found line, and is handled as usual
+			e.in.UnreadRune()
+			return false
+		}
+	}
+}
+
+// completes identifier before the cursor. Unique candidate is inserted whole,
+// otherwise their common prefix is inserted or all of them are listed
+func (e *editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+
+	start := e.cursor
+	for start > 0 && isIdentRune(e.buf[start-1]) {
+		start--
+	}
+	prefix := string(e.buf[start:e.cursor])
+	if prefix == "" {
+		return
+	}
+
+	candidates := e.complete(prefix)
+	switch len(candidates) {
+	case 0:
+		fmt.Fprint(e.out, "\a")
+	case 1:
+		e.insert([]rune(strings.TrimPrefix(candidates[0], prefix)))
+	default:
+		common := commonPrefix(candidates)
+		if len(common) > len(prefix) {
+			e.insert([]rune(strings.TrimPrefix(common, prefix)))
+			return
+		}
+		fmt.Fprintf(e.out, "\n%s\n", strings.Join(candidates, "  "))
+	}
+}
+
+// redraws prompt and line, then moves terminal cursor into place
+func (e *editor) refresh() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.buf))
+	if back := len(e.buf) - e.cursor; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd)
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,137 @@
+package repl
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testEditor(keys string, entries ...string) *editor {
+	complete := func(prefix string) []string {
+		candidates := []string{}
+		for _, name := range []string{"len", "let", "letter", "print"} {
+			if strings.HasPrefix(name, prefix) {
+				candidates = append(candidates, name)
+			}
+		}
+		return candidates
+	}
+	return newEditor(strings.NewReader(keys), io.Discard, &history{entries: entries}, complete)
+}
+
+func TestEditorReadLine(t *testing.T) {
+	tests := []struct {
+		keys     string
+		history  []string
+		expected string
+	}{
+		{"1 + 2\r", nil, "1 + 2"},
+		{"1 + 2\n", nil, "1 + 2"},
+		{"12\x7f3\r", nil, "13"},
+		{"bc\x01a\r", nil, "abc"},
+		{"ac\x1b[Db\r", nil, "abc"},
+		{"ab\x02\x02\x06\x06c\r", nil, "abc"},
+		{"abc\x1b[H\x1b[3~\r", nil, "bc"},
+		{"abc\x01\x1b[C\x0b\r", nil, "a"},
+		{"abc\x1b[D\x15\r", nil, "c"},
+		{"ab\x02\x04\r", nil, "a"},
+		{"\x1b[A\r", []string{"first", "second"}, "second"},
+		{"\x1b[A\x1b[A\r", []string{"first", "second"}, "first"},
+		{"\x1b[A\x1b[A\x1b[A\r", []string{"first", "second"}, "first"},
+		{"new\x1b[A\x1b[B\r", []string{"first"}, "new"},
+		{"\x10\x10\x0e\r", []string{"first", "second"}, "second"},
+		{"pr\t(1)\r", nil, "print(1)"},
+		{"l\t\r", nil, "le"},
+		{"le\t\r", nil, "le"},
+		{"x = lett\t\r", nil, "x = letter"},
+		{"zz\t\r", nil, "zz"},
+		{"\x12sec\r", []string{"second", "first", "secret"}, "secret"},
+		{"\x12sec\x12\r", []string{"second", "first", "secret"}, "second"},
+		{"\x12fi\x1b[D!\r", []string{"first", "second"}, "firs!t"},
+		{"old\x12fi\x07\r", []string{"first"}, "old"},
+		{"\x12zz\r", []string{"first"}, ""},
+	}
+
+	for _, tt := range tests {
+		e := testEditor(tt.keys, tt.history...)
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Errorf("keys %q - unexpected error: %s", tt.keys, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("keys %q - wrong line. expected=%q, got=%q", tt.keys, tt.expected, line)
+		}
+	}
+}
+
+func TestEditorInterruptAndEOF(t *testing.T) {
+	e := testEditor("abc\x03\x04")
+	if _, err := e.readLine(PROMPT); err != errInterrupted {
+		t.Errorf("expected errInterrupted on Ctrl-C, got=%v", err)
+	}
+	if _, err := e.readLine(PROMPT); err != io.EOF {
+		t.Errorf("expected io.EOF on Ctrl-D, got=%v", err)
+	}
+}
+
+func TestEditorAddsToHistory(t *testing.T) {
+	e := testEditor("one\rone\r\rtwo\r\x1b[A\x1b[A\r")
+	for i := 0; i < 5; i++ {
+		e.readLine(PROMPT)
+	}
+
+	expected := []string{"one", "two", "one"}
+	if strings.Join(e.history.entries, ",") != strings.Join(expected, ",") {
+		t.Errorf("wrong history. expected=%v, got=%v", expected, e.history.entries)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+
+	h := loadHistory(path)
+	if len(h.entries) != 0 {
+		t.Fatalf("expected empty history, got=%v", h.entries)
+	}
+	h.add("let x = 1;")
+	h.add("x + 1")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "let x = 1;\nx + 1\n" {
+		t.Errorf("wrong history file. got=%q", data)
+	}
+
+	loaded := loadHistory(path)
+	if strings.Join(loaded.entries, ",") != "let x = 1;,x + 1" {
+		t.Errorf("wrong loaded history. got=%v", loaded.entries)
+	}
+}
+
+func TestSessionCompletions(t *testing.T) {
+	s, _ := newSession(io.Discard, EVAL_ENGINE)
+	s.evalSource("let lenient = 1; let other = 2;")
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"le", []string{"len", "lenient", "let"}},
+		{"ot", []string{"other"}},
+		{"ty", []string{"typeOf"}},
+		{"f", []string{"false", "fn"}},
+		{"zz", []string{}},
+	}
+
+	for _, tt := range tests {
+		got := s.completions(tt.prefix)
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("completions(%q) wrong. expected=%v, got=%v", tt.prefix, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package repl
+
+import (
+	"fmt"
+
+	"github.com/Savvelius/go-interp/ast"
+	"github.com/Savvelius/go-interp/compiler"
+	"github.com/Savvelius/go-interp/evaluator"
+	"github.com/Savvelius/go-interp/object"
+	"github.com/Savvelius/go-interp/vm"
+)
+
+// names of engines for --engine flag and .engine command
+const (
+	EVAL_ENGINE = "eval" // tree-walking evaluator
+	VM_ENGINE   = "vm"   // compiler and virtual machine
+)
+
+// executes programs of a session, keeping definitions between them
+type engine interface {
+	eval(program *ast.Program) object.Object // nil if program doesn't produce a value
+	names() []string                         // names defined by the session, sorted
+	get(name string) (object.Object, bool)
+}
+
+func newEngine(name string) (engine, error) {
+	switch name {
+	case EVAL_ENGINE:
+		return &evalEngine{env: object.NewEnvironment()}, nil
+	case VM_ENGINE:
+		return newVMEngine(), nil
+	default:
+		return nil, fmt.Errorf("unknown engine %q, expected %s or %s", name, EVAL_ENGINE, VM_ENGINE)
+	}
+}
+
+type evalEngine struct {
+	env *object.Environment
+}
+
+func (e *evalEngine) eval(program *ast.Program) object.Object {
+	return evaluator.Eval(program, e.env)
+}
+
+func (e *evalEngine) names() []string { return e.env.Keys() }
+
+func (e *evalEngine) get(name string) (object.Object, bool) { return e.env.Get(name) }
+
+type vmEngine struct {
+	symbolTable *compiler.SymbolTable
+	constants   []object.Object
+	globals     []object.Object
+}
+
+func newVMEngine() *vmEngine {
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	return &vmEngine{
+		symbolTable: symbolTable,
+		constants:   []object.Object{},
+		globals:     make([]object.Object, vm.GlobalsSize),
+	}
+}
+
+// compile and runtime errors are returned as error objects. Names defined by
+// failed program are forgotten, so they never refer to unset globals
+func (e *vmEngine) eval(program *ast.Program) object.Object {
+	symbolTable := e.symbolTable.Clone()
+	comp := compiler.NewWithState(symbolTable, e.constants)
+	if err := comp.Compile(program); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+
+	bytecode := comp.Bytecode()
+	machine := vm.NewWithGlobalsStore(bytecode, e.globals)
+	if err := machine.Run(); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+
+	e.symbolTable = symbolTable
+	e.constants = bytecode.Constants
+
+	// last popped element is only meaningful after expression statement
+	if len(program.Statements) == 0 {
+		return nil
+	}
+	if _, ok := program.Statements[len(program.Statements)-1].(*ast.ExpressionStatement); !ok {
+		return nil
+	}
+	return machine.LastPoppedStackElem()
+}
+
+func (e *vmEngine) names() []string {
+	names := []string{}
+	for _, name := range e.symbolTable.Names() {
+		if symbol, _ := e.symbolTable.Resolve(name); symbol.Scope == compiler.GlobalScope {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (e *vmEngine) get(name string) (object.Object, bool) {
+	symbol, ok := e.symbolTable.Resolve(name)
+	if !ok || symbol.Scope != compiler.GlobalScope {
+		return nil, false
+	}
+	// slot of a name whose value was never stored
+	val := e.globals[symbol.Index]
+	return val, val != nil
+}
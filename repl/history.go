@@ -0,0 +1,73 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+)
+
+const (
+	HISTORY_FILE = ".monkey_history" // in user's home directory
+	maxHistory   = 1000
+)
+
+// previously entered lines, oldest first
+type history struct {
+	entries []string
+	path    string // file new entries are appended to, empty to keep them in memory only
+}
+
+// path of history file in home directory, empty if home is unknown
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HISTORY_FILE)
+}
+
+// reads history from path, missing file is an empty history
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		h.entries = append(h.entries, scanner.Text())
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	return h
+}
+
+// remembers line, empty lines and repeats of the last one are skipped
+func (h *history) add(line string) {
+	if line == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == line) {
+		return
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+
+	if h.path == "" {
+		return
+	}
+	// history is a convenience, failing to save it shouldn't break the session
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.WriteString(line + "\n")
+}
@@ -0,0 +1,56 @@
+package repl
+
+import (
+	"github.com/Savvelius/go-interp/lexer"
+	"github.com/Savvelius/go-interp/token"
+)
+
+// tokens that can't end a statement, input ending with one of them continues on next line
+var continuationTokens = map[token.TokenType]bool{
+	token.ASSIGN:   true,
+	token.PLUS:     true,
+	token.MINUS:    true,
+	token.BANG:     true,
+	token.ASTERISK: true,
+	token.SLASH:    true,
+	token.PERCENT:  true,
+	token.LT:       true,
+	token.GT:       true,
+	token.LT_EQ:    true,
+	token.GT_EQ:    true,
+	token.EQ:       true,
+	token.NOT_EQ:   true,
+	token.AND:      true,
+	token.OR:       true,
+	token.COMMA:    true,
+	token.COLON:    true,
+	token.LET:      true,
+	token.IF:       true,
+	token.ELSE:     true,
+	token.FUNCTION: true,
+}
+
+// reports whether src needs more lines to form complete statement: it has
+// unclosed parens, brackets, braces, string or comment, or ends with an operator
+func incomplete(src string) bool {
+	l := lexer.New(src)
+	depth := 0
+	var last token.Token
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			depth--
+		case token.ILLEGAL:
+			// block comment or string that isn't closed yet
+			if tok.Err != nil && tok.Err.Incomplete {
+				return true
+			}
+		}
+		last = tok
+	}
+
+	return depth > 0 || continuationTokens[last.Type]
+}
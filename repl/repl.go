@@ -4,47 +4,176 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/Savvelius/go-interp/evaluator"
 	"github.com/Savvelius/go-interp/lexer"
 	"github.com/Savvelius/go-interp/object"
 	"github.com/Savvelius/go-interp/parser"
+	"github.com/Savvelius/go-interp/token"
 )
 
-const PROMPT = ">> "
+const (
+	PROMPT              = ">> "
+	CONTINUATION_PROMPT = "... "
+)
 
+// reads input from in, evaluates it and writes results to out. If in is
+// a terminal, lines are edited in place and history is kept in HISTORY_FILE
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
-
-	for {
-		fmt.Print(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
-		}
+	StartWithEngine(in, out, EVAL_ENGINE)
+}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
+// same as Start, input is executed by named engine: EVAL_ENGINE or VM_ENGINE
+func StartWithEngine(in io.Reader, out io.Writer, engineName string) error {
+	s, err := newSession(out, engineName)
+	if err != nil {
+		return err
+	}
+
+	var r lineReader = &scannerReader{scanner: bufio.NewScanner(in), out: out}
+	if f, ok := in.(*os.File); ok && isTerminal(f.Fd()) {
+		ed := newEditor(f, out, loadHistory(historyPath()), s.completions)
+		r = &terminalReader{fd: f.Fd(), editor: ed, fallback: r}
+	}
 
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+	for !s.done {
+		line, err := r.readLine(PROMPT)
+		if err == errInterrupted {
 			continue
 		}
+		if err != nil {
+			return nil
+		}
+
+		if isCommand(line) {
+			s.runCommand(line)
+			continue
+		}
+
+		// empty line submits incomplete input as is, to get out of continuation
+		for incomplete(line) {
+			next, err := r.readLine(CONTINUATION_PROMPT)
+			if err == errInterrupted {
+				line = ""
+				break
+			}
+			if err != nil || next == "" {
+				break
+			}
+			line += "\n" + next
+		}
+
+		s.printResult(s.evalSource(line))
+	}
+	return nil
+}
+
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+// reads plain lines, used when input isn't a terminal
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scannerReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
 		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// switches terminal to raw mode only while line is edited, so evaluated
+// code prints as usual
+type terminalReader struct {
+	fd       uintptr
+	editor   *editor
+	fallback lineReader // used if terminal can't be switched to raw mode
+}
+
+func (r *terminalReader) readLine(prompt string) (string, error) {
+	restore, err := makeRaw(r.fd)
+	if err != nil {
+		return r.fallback.readLine(prompt)
+	}
+	defer restore()
+	return r.editor.readLine(prompt)
+}
+
+// state of one interactive session
+type session struct {
+	engine     engine
+	engineName string
+	out        io.Writer
+	inputs     []string // successfully parsed inputs, written by .save
+	done       bool     // set by .quit
+}
+
+func newSession(out io.Writer, engineName string) (*session, error) {
+	e, err := newEngine(engineName)
+	if err != nil {
+		return nil, err
+	}
+	return &session{engine: e, engineName: engineName, out: out}, nil
+}
+
+// parses and evaluates src in the session environment.
+// Returns nil if src has parse errors, they are printed
+func (s *session) evalSource(src string) object.Object {
+	l := lexer.New(src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		printParseErrors(s.out, p.Errors())
+		return nil
+	}
+
+	if strings.TrimSpace(src) != "" {
+		s.inputs = append(s.inputs, src)
+	}
+	return s.engine.eval(program)
+}
+
+// keywords, builtins and names defined in the session starting with prefix
+func (s *session) completions(prefix string) []string {
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, names := range [][]string{token.Keywords(), evaluator.BuiltinNames(), s.engine.names()} {
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) && !seen[name] {
+				seen[name] = true
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+func (s *session) printResult(evaluated object.Object) {
+	if evaluated == nil {
+		return
+	}
+	io.WriteString(s.out, evaluated.Inspect())
+	io.WriteString(s.out, "\n")
+	if err, ok := evaluated.(*object.Error); ok {
+		io.WriteString(s.out, err.StackTrace())
 	}
 }
 
-func printParseErrors(writer io.Writer, errors []string) {
+func printParseErrors(writer io.Writer, errors []*parser.Error) {
 	io.WriteString(writer, "Error:\n")
 	for _, err := range errors {
-		io.WriteString(writer, "\t"+err+"\n")
+		io.WriteString(writer, "\t"+err.String()+"\n")
 	}
 }
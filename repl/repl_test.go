@@ -0,0 +1,235 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 + 2", false},
+		{"let x = 5;", false},
+		{"", false},
+		{"let add = fn(x, y) {", true},
+		{"let add = fn(x, y) {\n x + y", true},
+		{"let add = fn(x, y) {\n x + y\n};", false},
+		{"[1, 2,", true},
+		{"[1, 2,\n3]", false},
+		{"add(1,", true},
+		{"{1: ", true},
+		{"1 +", true},
+		{"true and", true},
+		{"let x =", true},
+		{"if (x) { 1 } else", true},
+		{`"hello`, true},
+		{`"hello"`, false},
+		{`"{"`, false},
+		{"}", false},
+		{"1 /* comment", true},
+		{"1 /* comment */", false},
+		{"1 + // comment", true},
+		{"`raw", true},
+		{"`raw\nstring`", false},
+		{`"a\"b"`, false},
+		{`"a\"`, true},
+		{`"a\qb"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := incomplete(tt.input); got != tt.expected {
+			t.Errorf("incomplete(%q) wrong. expected=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStartMultiLine(t *testing.T) {
+	input := strings.Join([]string{
+		"let add = fn(x, y) {",
+		"  x +",
+		"  y",
+		"};",
+		"add(1,",
+		"2)",
+		"[1,",
+		"",
+		"3",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := ">> ... ... ... " +
+		">> ... 3\n" +
+		">> ... Error:\n\t1:4: no prefix function for EOF was found\n" +
+		">> 3\n" +
+		">> "
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.mk")
+	err := os.WriteFile(lib, []byte("let double = fn(x) { x * 2 };"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := filepath.Join(dir, "session.mk")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{".type 1 + 2", "INTEGER\n"},
+		{`.type "a"`, "STRING\n"},
+		{".type foo", "ERROR:1:1: identifier not found: foo\n"},
+		{".ast 1 + 2 * 3", "(1 + (2 * 3))\n"},
+		{".ast let = 1", "Error:\n\t1:5: expected next token to be IDENT, got = instead\n"},
+		{".tokens let x", "1:1    LET        \"let\"\n1:5    IDENT      \"x\"\n"},
+		{".type", "usage: .type <expr>\n"},
+		{".nope", "unknown command .nope, type .help for list of commands\n"},
+		{"let a = 1;\nlet b = [a];\n.env", "a = 1\nb = [1]\n"},
+		{"let b = 2; let a = 1;\n.env", "a = 1\nb = 2\n"},
+		{".load " + lib + "\ndouble(4)", "8\n"},
+		{"let x = 3;\n.reset\nx", "ERROR:1:1: identifier not found: x\n"},
+		{"1 + 1\n.quit\n2 + 2", "2\n"},
+		{"let y = 5;\n.save " + saved + "\n.reset\n.load " + saved + "\ny", "5\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+
+		got := strings.ReplaceAll(out.String(), PROMPT, "")
+		if got != tt.expected {
+			t.Errorf("input %q - wrong output.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestEnvWithVmEngine(t *testing.T) {
+	var out bytes.Buffer
+	err := StartWithEngine(strings.NewReader("let b = [1];\nlet a = a;\nlet c = fn(x) { x };\n.env"), &out, VM_ENGINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.ReplaceAll(out.String(), PROMPT, "")
+	expected := "ERROR:undefined variable a\nb = [1]\nc = Closure["
+	if !strings.HasPrefix(got, expected) {
+		t.Errorf("wrong output.\nexpected prefix=%q\ngot=%q", expected, got)
+	}
+
+	// name defined without stored value is left out
+	s, err := newSession(&out, VM_ENGINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.engine.(*vmEngine).symbolTable.Define("unset")
+	out.Reset()
+	s.listEnv("")
+	if out.String() != "" {
+		t.Errorf("unset global listed: %q", out.String())
+	}
+}
+
+func TestHelpListsAllCommands(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(".help"), &out)
+
+	for _, cmd := range commands {
+		if !strings.Contains(out.String(), "."+cmd.name) {
+			t.Errorf(".help doesn't mention .%s", cmd.name)
+		}
+	}
+}
+
+func TestEnginesAgree(t *testing.T) {
+	input := strings.Join([]string{
+		"let x = 5;",
+		"x * 2",
+		"let add = fn(a, b) { a + b };",
+		"add(x, 10)",
+		`let greet = fn(name) { "hi " + name };`,
+		`greet("monkey")`,
+		"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };",
+		"fib(10)",
+		"[1, 2, 3][1]",
+		`{"a": 1}["a"]`,
+		"len([1, 2])",
+		"if (false) { 1 }",
+		"true and x > 3",
+		"5 + true",
+		"let broken = 1 + true;",
+		"x",
+	}, "\n")
+
+	var evalOut, vmOut bytes.Buffer
+	if err := StartWithEngine(strings.NewReader(input), &evalOut, EVAL_ENGINE); err != nil {
+		t.Fatal(err)
+	}
+	if err := StartWithEngine(strings.NewReader(input), &vmOut, VM_ENGINE); err != nil {
+		t.Fatal(err)
+	}
+
+	// evaluator errors carry positions, vm ones don't
+	evalResult := evalOut.String()
+	for _, pos := range []string{"1:1: ", "1:14: "} {
+		evalResult = strings.ReplaceAll(evalResult, "ERROR:"+pos, "ERROR:")
+	}
+	if evalResult != vmOut.String() {
+		t.Errorf("engines disagree.\neval=%q\nvm=  %q", evalResult, vmOut.String())
+	}
+}
+
+func TestVMEngineKeepsState(t *testing.T) {
+	input := strings.Join([]string{
+		"let x = 1;",
+		"let y = undefined;",
+		"y",
+		"let f = fn() { x + 1 };",
+		"f()",
+		`let s = "a";`,
+		`s + "b"`,
+	}, "\n")
+
+	var out bytes.Buffer
+	StartWithEngine(strings.NewReader(input), &out, VM_ENGINE)
+
+	expected := "ERROR:undefined variable undefined\n" +
+		"ERROR:undefined variable y\n" +
+		"2\n" +
+		`"ab"` + "\n"
+	got := strings.ReplaceAll(out.String(), PROMPT, "")
+	if got != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, got)
+	}
+}
+
+func TestEngineCommand(t *testing.T) {
+	input := ".engine\nlet x = 1;\n.engine vm\n.engine\nx\n.engine nope\nlet y = 2;\ny"
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := "eval\n" +
+		"vm\n" +
+		"ERROR:undefined variable x\n" +
+		"unknown engine \"nope\", expected eval or vm\n" +
+		"2\n"
+	got := strings.ReplaceAll(out.String(), PROMPT, "")
+	if got != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, got)
+	}
+
+	if err := StartWithEngine(strings.NewReader(""), &out, "nope"); err == nil {
+		t.Errorf("expected error for unknown engine")
+	}
+}
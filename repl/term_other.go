@@ -0,0 +1,12 @@
+//go:build !linux
+
+package repl
+
+import "errors"
+
+// line editing needs raw terminal, elsewhere input is read line by line
+func isTerminal(fd uintptr) bool { return false }
+
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Savvelius/go-interp/evaluator"
+	"github.com/Savvelius/go-interp/object"
+)
+
+// run <file> [args...] or run -e <expr> [args...]: evaluates whole program,
+// args are available to it as `args` array of strings
+func run(args []string) int {
+	if len(args) == 0 || (args[0] == "-e" && len(args) < 2) {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	var name, src string
+	var scriptArgs []string
+	if args[0] == "-e" {
+		name, src, scriptArgs = "-e", args[1], args[2:]
+	} else {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		name, src, scriptArgs = args[0], string(data), args[1:]
+	}
+
+	program, err := parseSource(name, src)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	env := object.NewEnvironment()
+	env.Set("args", argsArray(scriptArgs))
+
+	evaluated := evaluator.Eval(program, env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		if errObj.Pos.IsValid() {
+			fmt.Fprintf(os.Stderr, "%s:%s: runtime error: %s\n", name, errObj.Pos, errObj.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: runtime error: %s\n", name, errObj.Message)
+		}
+		fmt.Fprint(os.Stderr, errObj.StackTrace())
+		return 1
+	}
+
+	// one-liners print their result, scripts print explicitly
+	if args[0] == "-e" && evaluated != nil && evaluated != evaluator.NULL {
+		fmt.Println(evaluated.Inspect())
+	}
+
+	return 0
+}
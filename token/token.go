@@ -1,10 +1,41 @@
 package token
 
+import (
+	"fmt"
+	"sort"
+)
+
 type TokenType string
 
 type Token struct {
 	Type    TokenType
-	Literal string // source code representing token of given type
+	Literal string   // source code representing token of given type
+	Pos     Position // position of the first character
+	End     Position // position right after the last character
+	Err     *Error   // why ILLEGAL token is illegal, nil for other tokens
+}
+
+// mistake found by lexer
+type Error struct {
+	Pos        Position // where the mistake is, may be inside of the token
+	Msg        string
+	Incomplete bool // input ended before token was closed, more input may fix it
+}
+
+// location in source code. Zero value is an unknown position
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // starting at 1
+	Column int // in characters, starting at 1
+}
+
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 // Enumerating TokenTypes
@@ -13,9 +44,11 @@ const (
 	EOF     = "EOF"
 
 	// Identifiers + literals
-	IDENT  = "IDENT" // add, foobar, x, y, ...
-	INT    = "INT"   // 1343456
-	STRING = "STRING"
+	IDENT    = "IDENT" // add, foobar, x, y, ...
+	INT      = "INT"   // 1343456, 0xff, 1_000
+	FLOAT    = "FLOAT" // 3.14, 1e-9
+	STRING   = "STRING"
+	TEMPLATE = "TEMPLATE" // string with ${} interpolations, literal is its source between quotes
 
 	// Operators
 	ASSIGN   = "="
@@ -24,9 +57,12 @@ const (
 	MINUS    = "-"
 	SLASH    = "/"
 	ASTERISK = "*"
+	PERCENT  = "%"
 
 	LT     = "<"
 	GT     = ">"
+	LT_EQ  = "<="
+	GT_EQ  = ">="
 	EQ     = "=="
 	NOT_EQ = "!="
 
@@ -65,6 +101,16 @@ var keywords = map[string]TokenType{
 	"or":     OR,
 }
 
+// source literals of all keywords, sorted
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func LookUpIdent(ident string) TokenType {
 	if tok, ok := keywords[ident]; ok {
 		return tok
@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"github.com/Savvelius/go-interp/code"
+	"github.com/Savvelius/go-interp/object"
+)
+
+// call frame of a single closure invocation
+type Frame struct {
+	cl          *object.Closure
+	ip          int // index of instruction currently being executed
+	basePointer int // stack pointer before the call, locals live right above it
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
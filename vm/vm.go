@@ -0,0 +1,767 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/Savvelius/go-interp/code"
+	"github.com/Savvelius/go-interp/compiler"
+	"github.com/Savvelius/go-interp/object"
+)
+
+const StackSize = 2048
+const GlobalsSize = 65536 // OpSetGlobal operand is 2 bytes wide
+const MaxFrames = 1024
+
+var (
+	True  = &object.Boolean{Value: true}
+	False = &object.Boolean{Value: false}
+	Null  = &object.Null{}
+)
+
+type VM struct {
+	constants []object.Object
+
+	stack []object.Object
+	sp    int // always points to the next free slot. Top of stack is stack[sp-1]
+
+	globals []object.Object
+
+	frames      []*Frame
+	framesIndex int // points to the next free frame
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	return NewWithGlobalsStore(bytecode, make([]object.Object, GlobalsSize))
+}
+
+// creates vm that shares globals with previous runs, used by REPL
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
+	// main program is executed as if it was a body of a function
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
+	return &VM{
+		constants: bytecode.Constants,
+
+		stack: make([]object.Object, StackSize),
+		sp:    0,
+
+		globals: s,
+
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// returns element on top of the stack or nil if stack is empty
+func (vm *VM) StackTop() object.Object {
+	if vm.sp == 0 {
+		return nil
+	}
+	return vm.stack[vm.sp-1]
+}
+
+// returns element that was popped last. Popped elements aren't cleared,
+// so it's the one right above the top of the stack
+func (vm *VM) LastPoppedStackElem() object.Object {
+	return vm.stack[vm.sp]
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return fmt.Errorf("stack overflow")
+	}
+
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// fetch-decode-execute cycle over instructions of the current frame
+func (vm *VM) Run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			err := vm.push(vm.constants[constIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod:
+			err := vm.executeBinaryOperation(op)
+			if err != nil {
+				return err
+			}
+
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpGreaterThanOrEqual,
+			code.OpLessThan, code.OpLessThanOrEqual:
+			err := vm.executeComparison(op)
+			if err != nil {
+				return err
+			}
+
+		case code.OpTrue:
+			err := vm.push(True)
+			if err != nil {
+				return err
+			}
+
+		case code.OpFalse:
+			err := vm.push(False)
+			if err != nil {
+				return err
+			}
+
+		case code.OpNull:
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+
+		case code.OpBang:
+			err := vm.executeBangOperator()
+			if err != nil {
+				return err
+			}
+
+		case code.OpMinus:
+			err := vm.executeMinusOperator()
+			if err != nil {
+				return err
+			}
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			// loop increments ip, so stop right before the target
+			vm.currentFrame().ip = pos - 1
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.pop()
+			if !isTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpJumpNotTruthyOrPop:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			if !isTruthy(vm.StackTop()) {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
+
+		case code.OpJumpTruthyOrPop:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			if isTruthy(vm.StackTop()) {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			vm.globals[globalIndex] = vm.pop()
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			err := vm.push(vm.globals[globalIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			err := vm.push(vm.stack[frame.basePointer+int(localIndex)])
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			definition := object.Builtins[builtinIndex]
+			err := vm.push(definition.Builtin)
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(currentClosure.Free[freeIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpCurrentClosure:
+			err := vm.push(vm.currentFrame().cl)
+			if err != nil {
+				return err
+			}
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.pushClosure(int(constIndex), int(numFree))
+			if err != nil {
+				return err
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.executeCall(int(numArgs))
+			if err != nil {
+				return err
+			}
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			frame := vm.popFrame()
+			// -1 also removes the callee itself
+			vm.sp = frame.basePointer - 1
+
+			err := vm.push(returnValue)
+			if err != nil {
+				return err
+			}
+
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+
+			err := vm.push(array)
+			if err != nil {
+				return err
+			}
+
+		case code.OpInterpolate:
+			numParts := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			str := vm.buildInterpolatedString(vm.sp-numParts, vm.sp)
+			vm.sp = vm.sp - numParts
+
+			err := vm.push(str)
+			if err != nil {
+				return err
+			}
+
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp = vm.sp - numElements
+
+			err = vm.push(hash)
+			if err != nil {
+				return err
+			}
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+
+			err := vm.executeIndexExpression(left, index)
+			if err != nil {
+				return err
+			}
+
+		case code.OpPop:
+			vm.pop()
+
+		default:
+			return fmt.Errorf("unknown opcode %d", op)
+		}
+	}
+
+	return nil
+}
+
+func (vm *VM) push(obj object.Object) error {
+	if vm.sp >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+
+	vm.stack[vm.sp] = obj
+	vm.sp++
+
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[vm.sp-1]
+	vm.sp--
+	return obj
+}
+
+func (vm *VM) executeCall(numArgs int) error {
+	// callee sits right below its arguments
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return fmt.Errorf("not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return fmt.Errorf("mismatched number of arguments. expected %d got %d",
+			cl.Fn.NumParameters, numArgs)
+	}
+
+	// arguments become first locals of the new frame
+	frame := NewFrame(cl, vm.sp-numArgs)
+	err := vm.pushFrame(frame)
+	if err != nil {
+		return err
+	}
+
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	if vm.sp >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+
+	return nil
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+
+	result := builtin.Fn(args...)
+	// -1 also removes the callee itself
+	vm.sp = vm.sp - numArgs - 1
+
+	if err, ok := result.(*object.Error); ok {
+		return fmt.Errorf("%s", err.Message)
+	}
+	if result == nil {
+		return vm.push(Null)
+	}
+	return vm.push(result)
+}
+
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	// free variables were pushed right before OpClosure
+	free := make([]object.Object, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i]
+	}
+	vm.sp = vm.sp - numFree
+
+	return vm.push(&object.Closure{Fn: function, Free: free})
+}
+
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+
+	for i := startIndex; i < endIndex; i++ {
+		elements[i-startIndex] = vm.stack[i]
+	}
+
+	return &object.Array{Value: elements}
+}
+
+func (vm *VM) buildInterpolatedString(startIndex, endIndex int) object.Object {
+	var out bytes.Buffer
+	for i := startIndex; i < endIndex; i++ {
+		out.WriteString(object.Stringify(vm.stack[i]))
+	}
+	return &object.String{Value: out.String()}
+}
+
+// keys and values are laid out on the stack as key1, value1, key2, value2, ...
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	pairs := map[object.HashKey]object.HashPair{}
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("key is not hashable: %s", key.Type())
+		}
+
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: pairs}, nil
+}
+
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeArrayIndex(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return vm.executeHashIndex(left, index)
+	default:
+		return fmt.Errorf("index operator not supported: %s", left.Type())
+	}
+}
+
+// out of bounds access is an error, same as in evaluator
+func (vm *VM) executeArrayIndex(array, index object.Object) error {
+	elements := array.(*object.Array).Value
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 {
+		return fmt.Errorf("index is less that zero: %d", idx)
+	}
+	if idx >= int64(len(elements)) {
+		return fmt.Errorf("index out of bounds. index=%d, size=%d", idx, len(elements))
+	}
+
+	return vm.push(elements[idx])
+}
+
+func (vm *VM) executeHashIndex(hash, index object.Object) error {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("key is not hashable: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return vm.push(Null)
+	}
+
+	return vm.push(pair.Value)
+}
+
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	switch {
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+		return vm.executeBinaryIntegerOperation(op, left, right)
+	case isFloatOperation(left, right):
+		return vm.executeBinaryFloatOperation(op, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ && op == code.OpAdd:
+		leftVal := left.(*object.String).Value
+		rightVal := right.(*object.String).Value
+		return vm.push(&object.String{Value: leftVal + rightVal})
+	case left.Type() != right.Type():
+		return fmt.Errorf("type mismatch: %s %s %s", left.Type(), operatorSymbol(op), right.Type())
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operatorSymbol(op), right.Type())
+	}
+}
+
+func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.Object) error {
+	leftVal := left.(*object.Integer).Value
+	rightVal := right.(*object.Integer).Value
+
+	var result int64
+
+	switch op {
+	case code.OpAdd:
+		result = leftVal + rightVal
+	case code.OpSub:
+		result = leftVal - rightVal
+	case code.OpMul:
+		result = leftVal * rightVal
+	case code.OpDiv:
+		if rightVal == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result = leftVal / rightVal
+	case code.OpMod:
+		if rightVal == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result = leftVal % rightVal
+	default:
+		return fmt.Errorf("unknown integer operator: %d", op)
+	}
+
+	return vm.push(&object.Integer{Value: result})
+}
+
+// at least one operand is float and the other is a number, integer gets promoted
+func isFloatOperation(left, right object.Object) bool {
+	_, leftOk := object.ToFloat(left)
+	_, rightOk := object.ToFloat(right)
+	return leftOk && rightOk && (left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ)
+}
+
+func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Object) error {
+	leftVal, _ := object.ToFloat(left)
+	rightVal, _ := object.ToFloat(right)
+
+	var result float64
+
+	switch op {
+	case code.OpAdd:
+		result = leftVal + rightVal
+	case code.OpSub:
+		result = leftVal - rightVal
+	case code.OpMul:
+		result = leftVal * rightVal
+	case code.OpDiv:
+		if rightVal == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result = leftVal / rightVal
+	case code.OpMod:
+		if rightVal == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result = math.Mod(leftVal, rightVal)
+	default:
+		return fmt.Errorf("unknown float operator: %d", op)
+	}
+
+	return vm.push(&object.Float{Value: result})
+}
+
+func (vm *VM) executeComparison(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	if left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ {
+		return vm.executeIntegerComparison(op, left, right)
+	}
+
+	if isFloatOperation(left, right) {
+		return vm.executeFloatComparison(op, left, right)
+	}
+
+	if left.Type() != right.Type() {
+		return fmt.Errorf("type mismatch: %s %s %s", left.Type(), operatorSymbol(op), right.Type())
+	}
+
+	if left.Type() == object.STRING_OBJ {
+		return vm.executeStringComparison(op, left, right)
+	}
+
+	// booleans are singletons, so they are compared by their address
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operatorSymbol(op), right.Type())
+	}
+}
+
+func (vm *VM) executeStringComparison(op code.Opcode, left, right object.Object) error {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal == rightVal))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal != rightVal))
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operatorSymbol(op), right.Type())
+	}
+}
+
+func (vm *VM) executeIntegerComparison(op code.Opcode, left, right object.Object) error {
+	leftVal := left.(*object.Integer).Value
+	rightVal := right.(*object.Integer).Value
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal == rightVal))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal != rightVal))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftVal > rightVal))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal >= rightVal))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftVal < rightVal))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal <= rightVal))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
+}
+
+func (vm *VM) executeFloatComparison(op code.Opcode, left, right object.Object) error {
+	leftVal, _ := object.ToFloat(left)
+	rightVal, _ := object.ToFloat(right)
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal == rightVal))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal != rightVal))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftVal > rightVal))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal >= rightVal))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftVal < rightVal))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftVal <= rightVal))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
+}
+
+// source representation of operator, used to keep error messages in sync with evaluator
+func operatorSymbol(op code.Opcode) string {
+	switch op {
+	case code.OpAdd:
+		return "+"
+	case code.OpSub:
+		return "-"
+	case code.OpMul:
+		return "*"
+	case code.OpDiv:
+		return "/"
+	case code.OpMod:
+		return "%"
+	case code.OpEqual:
+		return "=="
+	case code.OpNotEqual:
+		return "!="
+	case code.OpGreaterThan:
+		return ">"
+	case code.OpGreaterThanOrEqual:
+		return ">="
+	case code.OpLessThan:
+		return "<"
+	case code.OpLessThanOrEqual:
+		return "<="
+	default:
+		return fmt.Sprintf("op(%d)", op)
+	}
+}
+
+func (vm *VM) executeBangOperator() error {
+	operand := vm.pop()
+
+	switch operand {
+	case True:
+		return vm.push(False)
+	case False:
+		return vm.push(True)
+	case Null:
+		return vm.push(True)
+	default:
+		return vm.push(False)
+	}
+}
+
+func (vm *VM) executeMinusOperator() error {
+	operand := vm.pop()
+
+	switch operand := operand.(type) {
+	case *object.Integer:
+		return vm.push(&object.Integer{Value: -operand.Value})
+	case *object.Float:
+		return vm.push(&object.Float{Value: -operand.Value})
+	default:
+		return fmt.Errorf("unknown operator: -%s", operand.Type())
+	}
+}
+
+// same rules as evaluator's isTruthy: only false and null are falsy
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return True
+	}
+	return False
+}
@@ -0,0 +1,615 @@
+package vm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Savvelius/go-interp/ast"
+	"github.com/Savvelius/go-interp/compiler"
+	"github.com/Savvelius/go-interp/lexer"
+	"github.com/Savvelius/go-interp/object"
+	"github.com/Savvelius/go-interp/parser"
+)
+
+type vmTestCase struct {
+	input    string
+	expected any
+}
+
+func parse(src string) *ast.Program {
+	l := lexer.New(src)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1", 1},
+		{"2", 2},
+		{"1; 2", 2},
+		{"1 + 2", 3},
+		{"1 - 2", -1},
+		{"1 * 2", 2},
+		{"4 / 2", 2},
+		{"50 / 2 * 2 + 10 - 5", 55},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"5 * (2 + 10)", 60},
+		{"7 % 3", 1},
+		{"2 + 7 % 3 * 2", 4},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5", 1.5},
+		{"-1.5", -1.5},
+		{"1.5 + 2.25", 3.75},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2.0},
+		{"7 / 2.0", 3.5},
+		{"7.5 % 2", 1.5},
+		{"1e3 - 1", 999.0},
+		{"0x10 + 0o10 + 0b10 + 1_000", 1026},
+		{"1.5 > 1", true},
+		{"1 < 1.5", true},
+		{"2.0 == 2", true},
+		{"2.5 != 2.5", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestComparisonOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 < 1", false},
+		{"1 > 1", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"1 != 2", true},
+		{"(1 < 2) == (2 > 1)", true},
+		{"(1 < 2) != (1 > 2)", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", true},
+		{"false", false},
+		{"true == true", true},
+		{"false == false", true},
+		{"true == false", false},
+		{"true != false", true},
+		{"(1 < 2) == true", true},
+		{"(1 > 2) == false", true},
+		{"!true", false},
+		{"!false", true},
+		{"!5", false},
+		{"!!true", true},
+		{"!!5", true},
+		{"!(if (false) { 5; })", true},
+		{"-5", -5},
+		{"-50 + 100 + -50", 0},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", 10},
+		{"if (true) { 10 } else { 20 }", 10},
+		{"if (false) { 10 } else { 20 } ", 20},
+		{"if (1) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 > 2) { 10 }", Null},
+		{"if (false) { 10 }", Null},
+		{"if (true) { }", Null},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"1 and 2", 2},
+		{"0 and 2", 2},
+		{"1 or 2", 1},
+		{"false or 2", 2},
+		{"false and 2", false},
+		{"if (false) { 1 } or 3", 3},
+		{"[1, 2, 3] and false", false},
+		{"true or false and 1", true},
+		{"let x = 0; (x != 0) and (10 / x > 1)", false},
+		{"let x = 0; (x == 0) or (10 / x > 1)", true},
+		{"let x = 5; (x != 0) and (10 / x)", 2},
+		{"let x = 0; x != 0 and 10 / x > 1", false},
+		{"let x = 0; x == 0 or 10 / x > 1", true},
+		{"1 < 2 and 3 > 4", false},
+		{"let f = fn(x) { x and x + 1 }; f(1) + f(2)", 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", 1},
+		{"let one = 1; let two = 2; one + two", 3},
+		{"let one = 1; let two = one + one; one + two", 3},
+		{"let x = 1; let x = x + 1; x", 2},
+		{"let f = fn() { let x = 1; let x = x * 10; x }; f()", 10},
+	}
+
+	runVmTests(t, tests)
+}
+
+// value of let can't refer to the name being bound, same as in evaluator
+func TestLetSelfReference(t *testing.T) {
+	tests := []string{
+		"let a = a; a",
+		"fn() { let a = a + 1; a }()",
+	}
+
+	for _, input := range tests {
+		comp := compiler.New()
+		err := comp.Compile(parse(input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q", input)
+		}
+		if err.Error() != "undefined variable a" {
+			t.Errorf("wrong compiler error for %q. got=%q", input, err)
+		}
+	}
+}
+
+func TestGlobalsStoreIsShared(t *testing.T) {
+	symbolTable := compiler.NewSymbolTable()
+	constants := []object.Object{}
+	globals := make([]object.Object, GlobalsSize)
+
+	for _, line := range []string{"let a = 5;", "let b = a * 2;", "a + b"} {
+		comp := compiler.NewWithState(symbolTable, constants)
+		err := comp.Compile(parse(line))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		bytecode := comp.Bytecode()
+		constants = bytecode.Constants
+
+		machine := NewWithGlobalsStore(bytecode, globals)
+		err = machine.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		if line == "a + b" {
+			testExpectedObject(t, 15, machine.LastPoppedStackElem())
+		}
+	}
+}
+
+func TestCallingFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let fivePlusTen = fn() { 5 + 10; }; fivePlusTen();", 15},
+		{"let early = fn() { return 99; 100; }; early();", 99},
+		{"let noReturn = fn() { }; noReturn();", Null},
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+		{
+			`
+			let globalSeed = 50;
+			let minusOne = fn() { let num = 1; globalSeed - num; }
+			let minusTwo = fn() { let num = 2; globalSeed - num; }
+			minusOne() + minusTwo();
+			`,
+			97,
+		},
+		{
+			`
+			let returnsOneReturner = fn() { let returnsOne = fn() { 1; }; returnsOne; };
+			returnsOneReturner()();
+			`,
+			1,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len([1, 2, 3])`, 3},
+		{`len({1: 2})`, 1},
+		{`typeOf("")`, "STRING"},
+		{`runeLen("héllo")`, 5},
+		{`print(1)`, Null},
+		{`let p = print; p(1, 2)`, Null},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`
+			let newClosure = fn(a) { fn() { a; }; };
+			let closure = newClosure(99);
+			closure();
+			`,
+			99,
+		},
+		{
+			`
+			let newAdder = fn(a, b) {
+				let c = a + b;
+				fn(d) { c + d };
+			};
+			let adder = newAdder(1, 2);
+			adder(8);
+			`,
+			11,
+		},
+		{
+			`
+			let newAdderOuter = fn(a, b) {
+				let c = a + b;
+				fn(d) {
+					let e = d + c;
+					fn(f) { e + f; };
+				};
+			};
+			let newAdderInner = newAdderOuter(1, 2)
+			let adder = newAdderInner(3);
+			adder(8);
+			`,
+			14,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRecursiveClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`
+			let countDown = fn(x) {
+				if (x == 0) {
+					return 0;
+				} else {
+					countDown(x - 1);
+				}
+			};
+			countDown(1);
+			`,
+			0,
+		},
+		{
+			`
+			let wrapper = fn() {
+				let countDown = fn(x) {
+					if (x == 0) {
+						return 0;
+					} else {
+						countDown(x - 1);
+					}
+				};
+				countDown(1);
+			};
+			wrapper();
+			`,
+			0,
+		},
+		{
+			`
+			let fibonacci = fn(x) {
+				if (x == 0) {
+					return 0;
+				} else {
+					if (x == 1) {
+						return 1;
+					} else {
+						fibonacci(x - 1) + fibonacci(x - 2);
+					}
+				}
+			};
+			fibonacci(15);
+			`,
+			610,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStringExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`"monkey"`, "monkey"},
+		{`"mon" + "key"`, "monkey"},
+		{`"mon" + "key" + "banana"`, "monkeybanana"},
+		{`"mon" == "mon"`, true},
+		{`"mon" != "mon"`, false},
+		{`"mon" + "key" == "monkey"`, true},
+		{`"${1}"`, "1"},
+		{`let name = "monkey"; "hello ${name}!"`, "hello monkey!"},
+		{`let xs = [1, "a"]; "${len(xs)} items: ${xs}, ${xs[0] + 1}"`, `2 items: [1, "a"], 2`},
+		{`"${true} ${if (false) { 1 }} ${{"k": "v"}["k"]}"`, "true null v"},
+		{`let f = fn(x) { "<${x}>" }; "${f("${1 + 1}")}"`, "<2>"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"[]", []int{}},
+		{"[1, 2, 3]", []int{1, 2, 3}},
+		{"[1 + 2, 3 * 4, 5 + 6]", []int{3, 12, 11}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestHashLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"{}", map[object.HashKey]int64{}},
+		{
+			"{1: 2, 2: 3}",
+			map[object.HashKey]int64{
+				(&object.Integer{Value: 1}).HashKey(): 2,
+				(&object.Integer{Value: 2}).HashKey(): 3,
+			},
+		},
+		{
+			`{"one": 10 - 9, "thr" + "ee": 6 / 2, true: 5}`,
+			map[object.HashKey]int64{
+				(&object.String{Value: "one"}).HashKey():   1,
+				(&object.String{Value: "three"}).HashKey(): 3,
+				True.HashKey(): 5,
+			},
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3][1]", 2},
+		{"[[1, 1, 1]][0][0]", 1},
+		{"let i = 0; [1][i];", 1},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"{1: 1, 2: 2}[1]", 1},
+		{"{1: 1, 2: 2}[2]", 2},
+		{"{1: 1}[0]", Null},
+		{"{}[0]", Null},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / 0", "division by zero"},
+		{"1 % 0", "division by zero"},
+		{"1.5 / 0", "division by zero"},
+		{`1 < "a"`, "type mismatch: INTEGER < STRING"},
+		{`"a" <= "b"`, "unknown operator: STRING <= STRING"},
+		// operands are evaluated left to right, so the left error is reported
+		{"fn() { 1 / 0 }() < fn() { 1 + true }()", "division by zero"},
+		{"1.5 + true", "type mismatch: FLOAT + BOOLEAN"},
+		{`1.5 + "a"`, "type mismatch: FLOAT + STRING"},
+		{"(1 < 2) + 1", "type mismatch: BOOLEAN + INTEGER"},
+		{"true + false", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"fn() { 1; }(1);", "mismatched number of arguments. expected 0 got 1"},
+		{"fn(a, b) { a + b; }(1);", "mismatched number of arguments. expected 2 got 1"},
+		{"1();", "not a function: INTEGER"},
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{"let f = fn(x) { f(x) }; f(1)", "stack overflow"},
+		{`"a" - "b"`, "unknown operator: STRING - STRING"},
+		{`"a" > "b"`, "unknown operator: STRING > STRING"},
+		{"[1, 2, 3][3]", "index out of bounds. index=3, size=3"},
+		{"[1, 2, 3][-1]", "index is less that zero: -1"},
+		{"1[0]", "index operator not supported: INTEGER"},
+		{`{"name": "Monkey"}[fn(x) { x }];`, "key is not hashable: CLOSURE"},
+		{`{fn(x) { x }: 1};`, "key is not hashable: CLOSURE"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		err := comp.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected vm error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong vm error. want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestUnknownOpcode(t *testing.T) {
+	vm := New(&compiler.Bytecode{Instructions: []byte{255}})
+	if err := vm.Run(); err == nil {
+		t.Fatalf("expected error for unknown opcode")
+	}
+}
+
+func runVmTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		stackElem := vm.LastPoppedStackElem()
+		testExpectedObject(t, tt.expected, stackElem)
+	}
+}
+
+func testExpectedObject(t *testing.T, expected any, actual object.Object) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		err := testIntegerObject(int64(expected), actual)
+		if err != nil {
+			t.Errorf("testIntegerObject failed: %s", err)
+		}
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
+	case bool:
+		err := testBooleanObject(expected, actual)
+		if err != nil {
+			t.Errorf("testBooleanObject failed: %s", err)
+		}
+	case string:
+		err := testStringObject(expected, actual)
+		if err != nil {
+			t.Errorf("testStringObject failed: %s", err)
+		}
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Errorf("object not Array: %T (%+v)", actual, actual)
+			return
+		}
+		if len(array.Value) != len(expected) {
+			t.Errorf("wrong num of elements. want=%d, got=%d",
+				len(expected), len(array.Value))
+			return
+		}
+		for i, expectedElem := range expected {
+			err := testIntegerObject(int64(expectedElem), array.Value[i])
+			if err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+	case map[object.HashKey]int64:
+		hash, ok := actual.(*object.Hash)
+		if !ok {
+			t.Errorf("object is not Hash. got=%T (%+v)", actual, actual)
+			return
+		}
+		if len(hash.Pairs) != len(expected) {
+			t.Errorf("hash has wrong number of Pairs. want=%d, got=%d",
+				len(expected), len(hash.Pairs))
+			return
+		}
+		for expectedKey, expectedValue := range expected {
+			pair, ok := hash.Pairs[expectedKey]
+			if !ok {
+				t.Errorf("no pair for given key in Pairs")
+				continue
+			}
+			err := testIntegerObject(expectedValue, pair.Value)
+			if err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+	case *object.Null:
+		if actual != Null {
+			t.Errorf("object is not Null: %T (%+v)", actual, actual)
+		}
+	}
+}
+
+func testIntegerObject(expected int64, actual object.Object) error {
+	result, ok := actual.(*object.Integer)
+	if !ok {
+		return fmt.Errorf("object is not Integer. got=%T (%+v)",
+			actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%d, want=%d",
+			result.Value, expected)
+	}
+	return nil
+}
+
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v)",
+			actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+	}
+	return nil
+}
+
+func testBooleanObject(expected bool, actual object.Object) error {
+	result, ok := actual.(*object.Boolean)
+	if !ok {
+		return fmt.Errorf("object is not Boolean. got=%T (%+v)",
+			actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%t, want=%t",
+			result.Value, expected)
+	}
+	return nil
+}
+
+func testStringObject(expected string, actual object.Object) error {
+	result, ok := actual.(*object.String)
+	if !ok {
+		return fmt.Errorf("object is not String. got=%T (%+v)",
+			actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%q, want=%q",
+			result.Value, expected)
+	}
+	return nil
+}